@@ -0,0 +1,339 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned when the circuit is open, or when the half-open probes are all in flight.
+var ErrOpen = errors.New("breaker: circuit open")
+
+// State of a circuit.
+type State int
+
+const (
+	// StateClosed lets every call through and counts the failures.
+	StateClosed State = iota
+	// StateOpen rejects every call until Config.OpenTimeout has elapsed.
+	StateOpen
+	// StateHalfOpen lets Config.HalfOpenMaxRequests probes through to decide whether the dependency recovered.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Counts are the outcomes observed in the current state (and Config.Interval while closed).
+type Counts struct {
+	Requests             uint32
+	Successes            uint32
+	Failures             uint32
+	ConsecutiveSuccesses uint32
+	ConsecutiveFailures  uint32
+}
+
+func (c *Counts) onSuccess() {
+	c.Successes++
+	c.ConsecutiveSuccesses++
+	c.ConsecutiveFailures = 0
+}
+
+func (c *Counts) onFailure() {
+	c.Failures++
+	c.ConsecutiveFailures++
+	c.ConsecutiveSuccesses = 0
+}
+
+// TripPolicy decides when a closed circuit opens, it is evaluated after each failure.
+type TripPolicy func(c Counts) bool
+
+// ConsecutiveFailures trips after n failures in a row.
+func ConsecutiveFailures(n uint32) TripPolicy {
+	return func(c Counts) bool {
+		return c.ConsecutiveFailures >= n
+	}
+}
+
+// FailureRatio trips when at least ratio of the calls failed, once minRequests calls were observed.
+func FailureRatio(ratio float64, minRequests uint32) TripPolicy {
+	return func(c Counts) bool {
+		return c.Requests >= minRequests && float64(c.Failures)/float64(c.Requests) >= ratio
+	}
+}
+
+// Config configures a Breaker. The zero value trips after 5 consecutive failures.
+type Config struct {
+	// Name identifies the breaker in the logs
+	Name string
+	// Policy opens the circuit (default: ConsecutiveFailures(5))
+	Policy TripPolicy
+	// Interval clears the counts of a closed circuit periodically (default: 1 minute)
+	Interval time.Duration
+	// OpenTimeout is how long the circuit stays open before probing (default: 30s)
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of probes, all of them must succeed to close the circuit (default: 1)
+	HalfOpenMaxRequests uint32
+	// Logger logs the state changes (default: none)
+	Logger *slog.Logger
+	// OnStateChange is called on every state change, outside of the breaker lock
+	OnStateChange func(name string, from, to State)
+}
+
+// Breaker is a circuit breaker, safe for concurrent use.
+//
+// Behavior:
+//   - Closed: calls go through, the Policy is evaluated on each failure and opens the circuit.
+//   - Open: calls fail fast with ErrOpen during OpenTimeout.
+//   - Half-open: up to HalfOpenMaxRequests probes go through, other calls get ErrOpen.
+//     A failed probe opens the circuit again, HalfOpenMaxRequests successes close it.
+//   - Outcomes of calls started before a state change are ignored.
+//
+// Example usage:
+//
+//	b := breaker.New(breaker.Config{Name: "payments", Policy: breaker.FailureRatio(0.5, 20), Logger: logger})
+//	err := b.Execute(func() error { return callPayments(ctx) })
+//	if errors.Is(err, breaker.ErrOpen) {
+//		// fallback
+//	}
+type Breaker struct {
+	cfg Config
+	now func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	counts     Counts
+	expiry     time.Time // end of the interval (closed) or of the timeout (open)
+}
+
+// New creates a closed breaker.
+func New(cfg Config) *Breaker {
+	if cfg.Policy == nil {
+		cfg.Policy = ConsecutiveFailures(5)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenMaxRequests == 0 {
+		cfg.HalfOpenMaxRequests = 1
+	}
+
+	b := &Breaker{cfg: cfg, now: time.Now}
+	b.expiry = b.now().Add(cfg.Interval)
+	return b
+}
+
+// Name returns Config.Name.
+func (b *Breaker) Name() string {
+	return b.cfg.Name
+}
+
+// State returns the current state.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	state, _, change := b.currentState(b.now())
+	b.mu.Unlock()
+
+	b.notify(change)
+	return state
+}
+
+// Counts returns the outcomes observed in the current state.
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.counts
+}
+
+// Allow reserves a call. It returns ErrOpen when the call must not be made,
+// otherwise done must be called exactly once with the outcome of the call.
+//
+// Example usage:
+//
+//	done, err := b.Allow()
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := call()
+//	done(err == nil)
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	now := b.now()
+	state, generation, change := b.currentState(now)
+
+	switch {
+	case state == StateOpen:
+		err = ErrOpen
+	case state == StateHalfOpen && b.counts.Requests >= b.cfg.HalfOpenMaxRequests:
+		// Every probe of this half-open period is already taken
+		err = ErrOpen
+	default:
+		b.counts.Requests++
+	}
+	b.mu.Unlock()
+
+	b.notify(change)
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.done(generation, success) })
+	}, nil
+}
+
+// Execute runs fn when the circuit allows it, any error of fn is a failure.
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	success := false
+	defer func() { done(success) }()
+
+	err = fn()
+	success = err == nil
+	return err
+}
+
+func (b *Breaker) done(generation uint64, success bool) {
+	b.mu.Lock()
+	now := b.now()
+	state, current, change := b.currentState(now)
+	if generation != current {
+		// The call started in a previous state
+		b.mu.Unlock()
+		b.notify(change)
+		return
+	}
+
+	if success {
+		b.counts.onSuccess()
+		if state == StateHalfOpen && b.counts.ConsecutiveSuccesses >= b.cfg.HalfOpenMaxRequests {
+			change = b.setState(StateClosed, now)
+		}
+	} else {
+		b.counts.onFailure()
+		if state == StateHalfOpen || b.cfg.Policy(b.counts) {
+			change = b.setState(StateOpen, now)
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(change)
+}
+
+// stateChange is reported outside of the lock.
+type stateChange struct {
+	from, to State
+}
+
+// currentState applies the time based transitions: the end of the closed interval
+// and of the open timeout. It must be called with the lock held.
+func (b *Breaker) currentState(now time.Time) (State, uint64, *stateChange) {
+	var change *stateChange
+	switch b.state {
+	case StateClosed:
+		if now.After(b.expiry) {
+			b.newGeneration(now)
+		}
+	case StateOpen:
+		if now.After(b.expiry) {
+			change = b.setState(StateHalfOpen, now)
+		}
+	}
+	return b.state, b.generation, change
+}
+
+// setState must be called with the lock held.
+func (b *Breaker) setState(state State, now time.Time) *stateChange {
+	if b.state == state {
+		return nil
+	}
+	change := &stateChange{from: b.state, to: state}
+	b.state = state
+	b.newGeneration(now)
+	return change
+}
+
+// newGeneration resets the counts, it must be called with the lock held.
+func (b *Breaker) newGeneration(now time.Time) {
+	b.generation++
+	b.counts = Counts{}
+
+	switch b.state {
+	case StateClosed:
+		b.expiry = now.Add(b.cfg.Interval)
+	case StateOpen:
+		b.expiry = now.Add(b.cfg.OpenTimeout)
+	default:
+		b.expiry = time.Time{}
+	}
+}
+
+func (b *Breaker) notify(change *stateChange) {
+	if change == nil {
+		return
+	}
+
+	if b.cfg.Logger != nil {
+		level := slog.LevelWarn
+		if change.to == StateClosed {
+			level = slog.LevelInfo
+		}
+		b.cfg.Logger.Log(context.Background(), level, "circuit breaker state changed",
+			slog.String("breaker", b.cfg.Name),
+			slog.String("from", change.from.String()),
+			slog.String("to", change.to.String()),
+		)
+	}
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.cfg.Name, change.from, change.to)
+	}
+}
+
+// Set holds one breaker per key (host, gRPC method...), created on first use with the same Config.
+type Set struct {
+	cfg Config
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewSet creates an empty set, Config.Name is replaced by the key of each breaker.
+func NewSet(cfg Config) *Set {
+	return &Set{cfg: cfg, breakers: make(map[string]*Breaker)}
+}
+
+// Get returns the breaker of key, creating it if needed.
+func (s *Set) Get(key string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[key]
+	if !ok {
+		cfg := s.cfg
+		cfg.Name = key
+		b = New(cfg)
+		s.breakers[key] = b
+	}
+	return b
+}
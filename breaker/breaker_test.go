@@ -0,0 +1,173 @@
+package breaker
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+var errBoom = errors.New("boom")
+
+// newTestBreaker returns a breaker driven by a manual clock.
+func newTestBreaker(cfg Config) (*Breaker, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New(cfg)
+	b.now = func() time.Time { return now }
+	b.expiry = now.Add(b.cfg.Interval)
+	return b, &now
+}
+
+func fail(b *Breaker) error {
+	return b.Execute(func() error { return errBoom })
+}
+
+func succeed(b *Breaker) error {
+	return b.Execute(func() error { return nil })
+}
+
+func TestBreakerTripsOnConsecutiveFailures(t *testing.T) {
+	ass := assert.New(t)
+	b, _ := newTestBreaker(Config{Policy: ConsecutiveFailures(3)})
+
+	ass.ErrorIs(fail(b), errBoom)
+	ass.ErrorIs(fail(b), errBoom)
+	ass.NoError(succeed(b))
+	ass.ErrorIs(fail(b), errBoom)
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateClosed, b.State())
+
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateOpen, b.State())
+	ass.ErrorIs(succeed(b), ErrOpen)
+}
+
+func TestBreakerTripsOnFailureRatio(t *testing.T) {
+	ass := assert.New(t)
+	b, _ := newTestBreaker(Config{Policy: FailureRatio(0.5, 4)})
+
+	ass.NoError(succeed(b))
+	ass.ErrorIs(fail(b), errBoom)
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateClosed, b.State(), "below the minimum number of requests")
+
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateOpen, b.State())
+}
+
+func TestBreakerHalfOpenCloses(t *testing.T) {
+	ass := assert.New(t)
+	b, now := newTestBreaker(Config{Policy: ConsecutiveFailures(1), OpenTimeout: time.Second, HalfOpenMaxRequests: 2})
+
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateOpen, b.State())
+
+	*now = now.Add(2 * time.Second)
+	ass.Equal(StateHalfOpen, b.State())
+
+	done1, err := b.Allow()
+	ass.NoError(err)
+	done2, err := b.Allow()
+	ass.NoError(err)
+	_, err = b.Allow()
+	ass.ErrorIs(err, ErrOpen, "only two probes")
+
+	done1(true)
+	ass.Equal(StateHalfOpen, b.State())
+	done2(true)
+	ass.Equal(StateClosed, b.State())
+}
+
+func TestBreakerHalfOpenFailureReopens(t *testing.T) {
+	ass := assert.New(t)
+	b, now := newTestBreaker(Config{Policy: ConsecutiveFailures(1), OpenTimeout: time.Second})
+
+	ass.ErrorIs(fail(b), errBoom)
+	*now = now.Add(2 * time.Second)
+
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateOpen, b.State())
+}
+
+func TestBreakerIgnoresStaleOutcomes(t *testing.T) {
+	ass := assert.New(t)
+	b, _ := newTestBreaker(Config{Policy: ConsecutiveFailures(1)})
+
+	stale, err := b.Allow()
+	ass.NoError(err)
+	ass.ErrorIs(fail(b), errBoom)
+	ass.Equal(StateOpen, b.State())
+
+	stale(true)
+	ass.Equal(StateOpen, b.State())
+}
+
+func TestBreakerIntervalClearsCounts(t *testing.T) {
+	ass := assert.New(t)
+	b, now := newTestBreaker(Config{Policy: ConsecutiveFailures(2), Interval: time.Minute})
+
+	ass.ErrorIs(fail(b), errBoom)
+	*now = now.Add(2 * time.Minute)
+	ass.ErrorIs(fail(b), errBoom)
+
+	ass.Equal(StateClosed, b.State())
+	ass.Equal(uint32(1), b.Counts().Failures)
+}
+
+func TestBreakerLogsStateChanges(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+	var changes []string
+	b, _ := newTestBreaker(Config{
+		Name:          "payments",
+		Policy:        ConsecutiveFailures(1),
+		Logger:        logger,
+		OnStateChange: func(name string, from, to State) { changes = append(changes, name+":"+from.String()+"->"+to.String()) },
+	})
+
+	ass.ErrorIs(fail(b), errBoom)
+
+	ass.Equal([]string{"payments:closed->open"}, changes)
+	ass.Contains(buf.String(), "circuit breaker state changed")
+	ass.Contains(buf.String(), "payments")
+}
+
+func TestSetCreatesOneBreakerPerKey(t *testing.T) {
+	ass := assert.New(t)
+	set := NewSet(Config{Policy: ConsecutiveFailures(1)})
+
+	ass.ErrorIs(fail(set.Get("a")), errBoom)
+
+	ass.Equal(StateOpen, set.Get("a").State())
+	ass.Equal(StateClosed, set.Get("b").State())
+	ass.Equal("b", set.Get("b").Name())
+}
+
+func TestBreakerIsSafeForConcurrentUse(t *testing.T) {
+	b := New(Config{Policy: FailureRatio(0.9, 1000)})
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if i%2 == 0 {
+					_ = fail(b)
+				} else {
+					_ = succeed(b)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, StateClosed, b.State())
+}
@@ -0,0 +1,90 @@
+package breaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InterceptorConfig configures UnaryClientInterceptor.
+type InterceptorConfig struct {
+	// Key selects the breaker of a call (default: KeyByMethod)
+	Key func(method string, cc *grpc.ClientConn) string
+	// IsFailure classifies the outcome of a call (default: DefaultIsGRPCFailure)
+	IsFailure func(err error) bool
+}
+
+// KeyByMethod uses one breaker per full gRPC method, e.g. "/payments.Service/Charge".
+func KeyByMethod(method string, _ *grpc.ClientConn) string {
+	return method
+}
+
+// KeyByTarget uses one breaker per connection target.
+func KeyByTarget(_ string, cc *grpc.ClientConn) string {
+	return cc.Target()
+}
+
+// DefaultIsGRPCFailure counts the codes telling that the server is unhealthy as failures:
+// Unavailable, DeadlineExceeded, ResourceExhausted, Internal, Unknown and DataLoss.
+// Client errors such as InvalidArgument or NotFound, and cancellations, are successes.
+func DefaultIsGRPCFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC client interceptor guarding each call with the breaker of its key.
+// Rejected calls are not sent and fail with an Unavailable status, the error also wraps ErrOpen
+// (errors.Is(err, breaker.ErrOpen)).
+//
+// Example usage:
+//
+//	breakers := breaker.NewSet(breaker.Config{Policy: breaker.FailureRatio(0.5, 20), Logger: logger})
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithUnaryInterceptor(breaker.UnaryClientInterceptor(breakers, breaker.InterceptorConfig{})),
+//	)
+func UnaryClientInterceptor(set *Set, cfg InterceptorConfig) grpc.UnaryClientInterceptor {
+	if cfg.Key == nil {
+		cfg.Key = KeyByMethod
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = DefaultIsGRPCFailure
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		key := cfg.Key(method, cc)
+		done, err := set.Get(key).Allow()
+		if err != nil {
+			return &openError{err: err, status: status.Newf(codes.Unavailable, "%v: %s", err, key)}
+		}
+
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(!cfg.IsFailure(err))
+		return err
+	}
+}
+
+// openError is a rejected call, read as an Unavailable status by gRPC and as ErrOpen by errors.Is.
+type openError struct {
+	err    error
+	status *status.Status
+}
+
+func (e *openError) Error() string {
+	return e.status.Err().Error()
+}
+
+func (e *openError) Unwrap() error {
+	return e.err
+}
+
+// GRPCStatus is used by status.FromError and status.Code.
+func (e *openError) GRPCStatus() *status.Status {
+	return e.status
+}
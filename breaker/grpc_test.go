@@ -0,0 +1,67 @@
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientInterceptorOpensPerMethod(t *testing.T) {
+	ass := assert.New(t)
+
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		if method == "/svc/Broken" {
+			return status.Error(codes.Unavailable, "down")
+		}
+		return nil
+	}
+
+	set := NewSet(Config{Policy: ConsecutiveFailures(2)})
+	interceptor := UnaryClientInterceptor(set, InterceptorConfig{})
+	ctx := context.Background()
+
+	ass.Error(interceptor(ctx, "/svc/Broken", nil, nil, nil, invoker))
+	ass.Error(interceptor(ctx, "/svc/Broken", nil, nil, nil, invoker))
+
+	err := interceptor(ctx, "/svc/Broken", nil, nil, nil, invoker)
+	ass.Equal(codes.Unavailable, status.Code(err))
+	ass.Contains(err.Error(), ErrOpen.Error())
+	ass.ErrorIs(err, ErrOpen)
+	ass.Equal(2, calls)
+
+	ass.NoError(interceptor(ctx, "/svc/Working", nil, nil, nil, invoker))
+}
+
+func TestDefaultIsGRPCFailure(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.True(DefaultIsGRPCFailure(status.Error(codes.DeadlineExceeded, "")))
+	ass.False(DefaultIsGRPCFailure(status.Error(codes.InvalidArgument, "")))
+	ass.False(DefaultIsGRPCFailure(status.Error(codes.Canceled, "")))
+	ass.False(DefaultIsGRPCFailure(nil))
+}
+
+func TestUnaryClientInterceptorRejectionIsAStatus(t *testing.T) {
+	ass := assert.New(t)
+
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.Unavailable, "down")
+	}
+	set := NewSet(Config{Policy: ConsecutiveFailures(1)})
+	interceptor := UnaryClientInterceptor(set, InterceptorConfig{})
+	_ = interceptor(context.Background(), "/svc/Broken", nil, nil, nil, invoker)
+
+	err := interceptor(context.Background(), "/svc/Broken", nil, nil, nil, invoker)
+	s, ok := status.FromError(err)
+	ass.True(ok)
+	ass.Equal(codes.Unavailable, s.Code())
+	ass.Equal("breaker: circuit open: /svc/Broken", s.Message())
+	ass.ErrorIs(fmt.Errorf("charge: %w", err), ErrOpen)
+}
@@ -0,0 +1,84 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// TransportConfig configures Transport.
+type TransportConfig struct {
+	// Key selects the breaker of a request (default: KeyByHost)
+	Key func(r *http.Request) string
+	// IsFailure classifies the outcome of a call (default: DefaultIsHTTPFailure)
+	IsFailure func(resp *http.Response, err error) bool
+}
+
+// KeyByHost uses one breaker per host (and port).
+func KeyByHost(r *http.Request) string {
+	return r.URL.Host
+}
+
+// KeyByHostAndMethod uses one breaker per host and HTTP method.
+func KeyByHostAndMethod(r *http.Request) string {
+	return r.Method + " " + r.URL.Host
+}
+
+// DefaultIsHTTPFailure counts network errors and 5xx responses as failures.
+// Calls canceled by the caller are not failures of the dependency.
+func DefaultIsHTTPFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// Transport returns an http.RoundTripper middleware guarding each request with the breaker of its key.
+// Rejected requests fail with an error wrapping ErrOpen and are not sent.
+//
+// Example usage:
+//
+//	breakers := breaker.NewSet(breaker.Config{Logger: logger})
+//	client := sdkhttp.NewClient().
+//		WithRetry(sdkhttp.RetryConfig{}).
+//		Use(breaker.Transport(breakers, breaker.TransportConfig{})).
+//		Build()
+func Transport(set *Set, cfg TransportConfig) func(http.RoundTripper) http.RoundTripper {
+	if cfg.Key == nil {
+		cfg.Key = KeyByHost
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = DefaultIsHTTPFailure
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return &transport{next: next, set: set, cfg: cfg}
+	}
+}
+
+type transport struct {
+	next http.RoundTripper
+	set  *Set
+	cfg  TransportConfig
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	key := t.cfg.Key(r)
+	done, err := t.set.Get(key).Allow()
+	if err != nil {
+		if r.Body != nil {
+			// A RoundTripper must close the body, even on errors
+			_ = r.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", err, key)
+	}
+
+	resp, err := t.next.RoundTrip(r)
+	done(!t.cfg.IsFailure(resp, err))
+	return resp, err
+}
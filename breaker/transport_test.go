@@ -0,0 +1,61 @@
+package breaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportOpensPerHost(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+
+	set := NewSet(Config{Policy: ConsecutiveFailures(2)})
+	client := &http.Client{Transport: Transport(set, TransportConfig{})(nil)}
+
+	for range 2 {
+		resp, err := client.Get(failing.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(failing.URL)
+	ass.ErrorIs(err, ErrOpen)
+	ass.Equal(int32(2), calls.Load())
+
+	resp, err := client.Get(healthy.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	ass.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func TestTransportIgnoresClientErrors(t *testing.T) {
+	ass := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	set := NewSet(Config{Policy: ConsecutiveFailures(1)})
+	client := &http.Client{Transport: Transport(set, TransportConfig{})(nil)}
+
+	for range 3 {
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	ass.Equal(StateClosed, set.Get(KeyByHost(httptest.NewRequest(http.MethodGet, server.URL, nil))).State())
+}
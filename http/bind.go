@@ -0,0 +1,381 @@
+package http
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMultipartMemory is the part of a multipart body kept in memory, as in net/http.
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// BindConfig configures BindWith.
+type BindConfig struct {
+	// DisallowUnknownFields rejects JSON and form bodies with fields that T does not declare
+	DisallowUnknownFields bool
+}
+
+// Bind decodes the request into a T struct and validates it, see BindWith.
+//
+// Example usage:
+//
+//	type CreateUser struct {
+//		OrgID string `path:"org"`
+//		DryRun bool  `query:"dry_run"`
+//		Email string `json:"email" form:"email" validate:"required,email"`
+//		Name  string `json:"name" form:"name" validate:"required,min=1,max=100"`
+//	}
+//
+//	mux.HandleFunc("POST /orgs/{org}/users", JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+//		req, resp := Bind[CreateUser](r)
+//		if resp != nil {
+//			return resp
+//		}
+//		...
+//	}))
+func Bind[T any](r *http.Request) (T, *Response) {
+	return BindWith[T](r, BindConfig{})
+}
+
+// BindWith decodes the request into a T struct and validates it.
+//
+// Behavior:
+//   - Decodes the body according to its Content-Type: JSON (and "+json" suffix types)
+//     with the json tags, form and multipart bodies with the form tags.
+//     Other content types answer 415 (UnsupportedMediaType payload).
+//     The body is read within the BodyLimits set by BodyLimitMiddleware (413 past MaxBytes).
+//   - Then sets the fields tagged `query:"name"` from the query string and
+//     `path:"name"` from the path values of the route pattern (Request.PathValue).
+//     Unless they also have a json tag, these fields are never set from the body.
+//     Supported field types: strings, booleans, numbers, time.Duration, time.Time (RFC 3339),
+//     encoding.TextUnmarshaler, pointers and slices of those.
+//   - Malformed values answer 400 and well-formed values breaking the `validate` tags
+//     answer 422 (see Validate). Both list every invalid field:
+//     {"message": "...", "details": "2 invalid fields", "errors": [{"field", "rule", "message"}]}
+//
+// ⚠️ T must be a struct, otherwise BindWith panics.
+func BindWith[T any](r *http.Request, cfg BindConfig) (T, *Response) {
+	var v, zero T
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() != reflect.Struct {
+		panic("http: Bind expects a struct type, got " + rv.Type().String())
+	}
+	mustCheckValidation(rv.Type())
+
+	errs, resp := bindBody(r, &v, rv, cfg)
+	if resp != nil {
+		return zero, resp
+	}
+
+	query := r.URL.Query()
+	errs = append(errs, bindValues(rv, "", "query", func(name string) []string {
+		return query[name]
+	})...)
+	errs = append(errs, bindValues(rv, "", "path", func(name string) []string {
+		if value := r.PathValue(name); value != "" {
+			return []string{value}
+		}
+		return nil
+	})...)
+	if len(errs) > 0 {
+		return zero, fieldErrorsResponse(http.StatusBadRequest, errs)
+	}
+
+	if err := Validate(v); err != nil {
+		var fieldErrs FieldErrors
+		if errors.As(err, &fieldErrs) {
+			return zero, fieldErrorsResponse(http.StatusUnprocessableEntity, fieldErrs)
+		}
+		return zero, UnprocessableEntity(err.Error())
+	}
+	return v, nil
+}
+
+// bindBody decodes the body into ptr, returning field errors (400) or a final response.
+func bindBody(r *http.Request, ptr any, rv reflect.Value, cfg BindConfig) (FieldErrors, *Response) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, UnsupportedMediaType("invalid content type")
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		body, err := readLimitedBody(r.Body, bodyLimitsFromContext(r.Context()).MaxBytes)
+		if errors.Is(err, errBodyTooLarge) {
+			return nil, RequestEntityTooLarge("body too large")
+		}
+		if err != nil {
+			return nil, BadRequest("unreadable body")
+		}
+		r.Body = readCloser{Reader: bytes.NewReader(body), Closer: r.Body}
+		if len(bytes.TrimSpace(body)) == 0 {
+			return nil, nil
+		}
+		errs, resp := decodeJSONInto(body, ptr, cfg)
+		// encoding/json matches field names case-insensitively, a body key must not set
+		// a value that only the query string or the path may set
+		zeroTaggedFields(rv, "query", "path")
+		return errs, resp
+
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		if maxBytes := bodyLimitsFromContext(r.Context()).MaxBytes; maxBytes > 0 {
+			r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+		}
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(defaultMultipartMemory)
+		} else {
+			err = r.ParseForm()
+		}
+		if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+			return nil, RequestEntityTooLarge("body too large")
+		}
+		if err != nil {
+			return nil, BadRequest("malformed form body")
+		}
+
+		form := r.PostForm
+		if r.MultipartForm != nil {
+			form = r.MultipartForm.Value
+		}
+		var errs FieldErrors
+		if cfg.DisallowUnknownFields {
+			known := taggedNames(rv.Type(), "form")
+			for name := range form {
+				if !known[name] {
+					errs = append(errs, FieldError{Field: name, Rule: "unknown", Message: "is not a known field"})
+				}
+			}
+		}
+		errs = append(errs, bindValues(rv, "", "form", func(name string) []string {
+			return form[name]
+		})...)
+		return errs, nil
+
+	default:
+		return nil, UnsupportedMediaType("unsupported content type " + mediaType)
+	}
+}
+
+// decodeJSONInto decodes a single JSON document, type mismatches and unknown fields are field errors.
+func decodeJSONInto(body []byte, ptr any, cfg BindConfig) (FieldErrors, *Response) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if cfg.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(ptr)
+	if err == nil {
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return nil, BadRequest("unexpected data after the JSON document")
+		}
+		return nil, nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := typeErr.Field
+		if field == "" {
+			return nil, BadRequest("malformed JSON body: " + err.Error())
+		}
+		return FieldErrors{{Field: field, Rule: "type", Message: "must be a " + jsonTypeName(typeErr.Type)}}, nil
+	}
+	// The encoding/json error for unknown fields has no dedicated type
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return FieldErrors{{Field: strings.Trim(name, `"`), Rule: "unknown", Message: "is not a known field"}}, nil
+	}
+	return nil, BadRequest("malformed JSON body: " + err.Error())
+}
+
+// jsonTypeName describes a Go type with the JSON vocabulary.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return t.String()
+	}
+}
+
+// taggedNames returns the names declared by a tag key, embedded structs included.
+func taggedNames(t reflect.Type, key string) map[string]bool {
+	names := make(map[string]bool)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get(key) == "" {
+			for name := range taggedNames(f.Type, key) {
+				names[name] = true
+			}
+			continue
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// zeroTaggedFields resets the fields tagged with one of keys that have no json tag.
+func zeroTaggedFields(v reflect.Value, keys ...string) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			zeroTaggedFields(v.Field(i), keys...)
+			continue
+		}
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+			continue
+		}
+		for _, key := range keys {
+			if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+				v.Field(i).SetZero()
+				break
+			}
+		}
+	}
+}
+
+// bindValues sets the fields tagged with key from the string values returned by lookup.
+func bindValues(v reflect.Value, prefix, key string, lookup func(name string) []string) FieldErrors {
+	var errs FieldErrors
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Tag.Get(key) == "" {
+			errs = append(errs, bindValues(v.Field(i), prefix, key, lookup)...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		values := lookup(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := setFromStrings(v.Field(i), values); err != nil {
+			errs = append(errs, FieldError{Field: prefix + name, Rule: "type", Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	durationType        = reflect.TypeFor[time.Duration]()
+	timeType            = reflect.TypeFor[time.Time]()
+)
+
+// setFromStrings converts values into v, slices take every value, other types the first one.
+func setFromStrings(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFromString(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setFromString(v, values[0])
+}
+
+func setFromString(v reflect.Value, value string) error {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setFromString(ptr.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.Type() != timeType && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return errors.New("is not a valid value")
+		}
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("must be a duration, e.g. 1m30s")
+		}
+		v.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return errors.New("must be an RFC 3339 date")
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a positive integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return errors.New("has an unsupported type " + v.Type().String())
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindUser struct {
+	Org    string        `path:"org"`
+	DryRun bool          `query:"dry_run"`
+	Tags   []string      `query:"tag"`
+	TTL    time.Duration `query:"ttl"`
+	Email  string        `json:"email" form:"email" validate:"required,email"`
+	Name   string        `json:"name" form:"name" validate:"required,min=1,max=10"`
+	Age    *int          `json:"age" form:"age" validate:"omitempty,min=18"`
+}
+
+// serveBind routes the request through a mux so that path values are set.
+func serveBind[T any](t *testing.T, cfg BindConfig, req *http.Request) (T, *Response) {
+	t.Helper()
+
+	var got T
+	var resp *Response
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/{org}/users", func(w http.ResponseWriter, r *http.Request) {
+		got, resp = BindWith[T](r, cfg)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	return got, resp
+}
+
+func fieldErrorsOf(t *testing.T, resp *Response) FieldErrors {
+	t.Helper()
+	payload, ok := resp.Payload.(map[string]any)
+	require.True(t, ok)
+	errs, ok := payload["errors"].(FieldErrors)
+	require.True(t, ok)
+	return errs
+}
+
+func TestBindJSONQueryAndPath(t *testing.T) {
+	ass := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users?dry_run=true&tag=a&tag=b&ttl=1m", strings.NewReader(`{"email":"bob@example.com","name":"Bob","age":30}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	got, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.Nil(t, resp)
+
+	ass.Equal("acme", got.Org)
+	ass.True(got.DryRun)
+	ass.Equal([]string{"a", "b"}, got.Tags)
+	ass.Equal(time.Minute, got.TTL)
+	ass.Equal("bob@example.com", got.Email)
+	ass.Equal("Bob", got.Name)
+	ass.Equal(30, *got.Age)
+}
+
+func TestBindForm(t *testing.T) {
+	ass := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader("email=bob%40example.com&name=Bob&age=20"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	got, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.Nil(t, resp)
+	ass.Equal("bob@example.com", got.Email)
+	ass.Equal(20, *got.Age)
+}
+
+func TestBindMultipartForm(t *testing.T) {
+	ass := assert.New(t)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("email", "bob@example.com")
+	_ = mw.WriteField("name", "Bob")
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	got, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.Nil(t, resp)
+	ass.Equal("Bob", got.Name)
+}
+
+func TestBindListsEveryValidationError(t *testing.T) {
+	ass := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader(`{"email":"not-an-email","name":"","age":12}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	_, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.NotNil(t, resp)
+	ass.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+
+	errs := fieldErrorsOf(t, resp)
+	ass.Equal(FieldErrors{
+		{Field: "email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "name", Rule: "required", Message: "is required"},
+		{Field: "age", Rule: "min", Message: "must be at least 18"},
+	}, errs)
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, resp)
+	ass.JSONEq(`{
+		"code": "validation_failed",
+		"message": "unprocessable entity",
+		"details": "3 invalid fields",
+		"errors": [
+			{"field": "email", "rule": "email", "message": "must be a valid email address"},
+			{"field": "name", "rule": "required", "message": "is required"},
+			{"field": "age", "rule": "min", "message": "must be at least 18"}
+		]
+	}`, rec.Body.String())
+}
+
+func TestBindMalformedValues(t *testing.T) {
+	ass := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users?dry_run=maybe", strings.NewReader(`{"email":"bob@example.com","name":"Bob","age":"old"}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	_, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.NotNil(t, resp)
+	ass.Equal(http.StatusBadRequest, resp.StatusCode)
+	ass.Equal(FieldErrors{
+		{Field: "age", Rule: "type", Message: "must be a number"},
+		{Field: "dry_run", Rule: "type", Message: "must be a boolean"},
+	}, fieldErrorsOf(t, resp))
+}
+
+func TestBindMalformedJSON(t *testing.T) {
+	ass := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader(`{"email":`))
+	req.Header.Set("Content-Type", "application/json")
+
+	_, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.NotNil(t, resp)
+	ass.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestBindDisallowUnknownFields(t *testing.T) {
+	ass := assert.New(t)
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader(`{"email":"bob@example.com","name":"Bob","admin":true}`))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	_, resp := serveBind[bindUser](t, BindConfig{}, newReq())
+	ass.Nil(resp)
+
+	_, resp = serveBind[bindUser](t, BindConfig{DisallowUnknownFields: true}, newReq())
+	require.NotNil(t, resp)
+	ass.Equal(http.StatusBadRequest, resp.StatusCode)
+	ass.Equal(FieldErrors{{Field: "admin", Rule: "unknown", Message: "is not a known field"}}, fieldErrorsOf(t, resp))
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader("email=bob%40example.com&name=Bob&admin=1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, resp = serveBind[bindUser](t, BindConfig{DisallowUnknownFields: true}, req)
+	require.NotNil(t, resp)
+	ass.Equal("admin", fieldErrorsOf(t, resp)[0].Field)
+}
+
+func TestBindUnsupportedContentType(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader(`<user/>`))
+	req.Header.Set("Content-Type", "application/xml")
+
+	_, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestBindBodyTooLarge(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader(`{"name":"`+strings.Repeat("a", 100)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), bodyLimitsKey{}, BodyLimits{MaxBytes: 10}))
+
+	_, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestValidateNestedStructs(t *testing.T) {
+	ass := assert.New(t)
+
+	type item struct {
+		SKU      string `json:"sku" validate:"required,len=6"`
+		Quantity int    `json:"quantity" validate:"min=1"`
+	}
+	type order struct {
+		Currency string `json:"currency" validate:"oneof=EUR USD"`
+		Items    []item `json:"items" validate:"required,max=2"`
+		Callback string `json:"callback" validate:"omitempty,url"`
+	}
+
+	var o order
+	ass.NoError(json.Unmarshal([]byte(`{"currency":"GBP","items":[{"sku":"ABC123","quantity":1},{"sku":"X","quantity":0}],"callback":"nope"}`), &o))
+
+	err := Validate(o)
+	var errs FieldErrors
+	require.ErrorAs(t, err, &errs)
+	ass.Equal(FieldErrors{
+		{Field: "currency", Rule: "oneof", Message: "must be one of: EUR, USD"},
+		{Field: "items[1].sku", Rule: "len", Message: "must have exactly 6 characters"},
+		{Field: "items[1].quantity", Rule: "min", Message: "must be at least 1"},
+		{Field: "callback", Rule: "url", Message: "must be an absolute URL"},
+	}, errs)
+
+	ass.NoError(Validate(order{Currency: "EUR", Items: []item{{SKU: "ABC123", Quantity: 2}}}))
+}
+
+func TestValidateUnknownRulePanics(t *testing.T) {
+	type invalid struct {
+		Name string `validate:"shiny"`
+	}
+	assert.Panics(t, func() { _ = Validate(invalid{}) })
+}
+
+func TestBindBodyCannotSetQueryOrPathFields(t *testing.T) {
+	ass := assert.New(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/orgs/acme/users", strings.NewReader(`{"email":"bob@example.com","name":"Bob","DryRun":true,"org":"evil","tags":["admin"]}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	got, resp := serveBind[bindUser](t, BindConfig{}, req)
+	require.Nil(t, resp)
+
+	ass.Equal("acme", got.Org)
+	ass.False(got.DryRun)
+	ass.Empty(got.Tags)
+}
+
+func TestValidateChecksRulesWhenTagsAreParsed(t *testing.T) {
+	ass := assert.New(t)
+
+	type nested struct {
+		Code string `validate:"min=abc"`
+	}
+	type parent struct {
+		Children []*nested `json:"children"`
+	}
+	// The nested struct is checked even though no child is set
+	ass.Panics(func() { _ = Validate(parent{}) })
+
+	type wrongKind struct {
+		At time.Time `validate:"email"`
+	}
+	ass.Panics(func() { _ = Validate(wrongKind{}) })
+
+	type emptyOneOf struct {
+		Mode string `validate:"oneof="`
+	}
+	ass.Panics(func() { _ = Validate(emptyOneOf{}) })
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+// BodyDecoder decodes a raw request body into a generic value (maps, slices,
+// strings...) that can be passed to Sanitize and logged.
+// params holds the media type parameters of the Content-Type header (e.g. the multipart boundary).
+type BodyDecoder func(body []byte, params map[string]string) (any, error)
+
+// DefaultBodyDecoders returns the decoders used by LogJSONBodyMiddleware, keyed by media type.
+// Types with a "+json" suffix (e.g. application/problem+json) fall back to the "application/json" decoder.
+func DefaultBodyDecoders() map[string]BodyDecoder {
+	return map[string]BodyDecoder{
+		"application/json":                  DecodeJSONBody,
+		"application/x-www-form-urlencoded": DecodeFormBody,
+		"multipart/form-data":               DecodeMultipartBody,
+		"application/xml":                   DecodeXMLBody,
+		"text/xml":                          DecodeXMLBody,
+		"application/x-ndjson":              DecodeNDJSONBody,
+	}
+}
+
+// findBodyDecoder resolves the decoder matching the Content-Type header.
+func findBodyDecoder(decoders map[string]BodyDecoder, contentType string) (BodyDecoder, map[string]string, bool) {
+	if contentType == "" {
+		return nil, nil, false
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, nil, false
+	}
+	if d, ok := decoders[mediaType]; ok {
+		return d, params, true
+	}
+	if strings.HasSuffix(mediaType, "+json") {
+		if d, ok := decoders["application/json"]; ok {
+			return d, params, true
+		}
+	}
+	return nil, nil, false
+}
+
+// DecodeJSONBody decodes a single JSON document.
+func DecodeJSONBody(body []byte, _ map[string]string) (any, error) {
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// DecodeNDJSONBody decodes newline-delimited JSON into a list of documents.
+// Blank lines are skipped.
+func DecodeNDJSONBody(body []byte, _ map[string]string) (any, error) {
+	var items []any
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var item any
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// DecodeFormBody decodes an application/x-www-form-urlencoded body.
+// Single values are returned as strings, repeated keys as lists.
+func DecodeFormBody(body []byte, _ map[string]string) (any, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	return valuesToMap(values), nil
+}
+
+// DecodeMultipartBody describes a multipart/form-data body.
+// Regular fields are returned with their values, file parts only expose their field name,
+// file name, content type and size: file contents are never kept.
+func DecodeMultipartBody(body []byte, params map[string]string) (any, error) {
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, errors.New("multipart: missing boundary")
+	}
+
+	fields := make(map[string][]string)
+	files := make([]any, 0)
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FileName() != "" {
+			size, err := io.Copy(io.Discard, part)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, map[string]any{
+				"field":        part.FormName(),
+				"filename":     part.FileName(),
+				"content_type": part.Header.Get("Content-Type"),
+				"size":         size,
+			})
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxObservedBytes))
+		if err != nil {
+			return nil, err
+		}
+		fields[part.FormName()] = append(fields[part.FormName()], string(value))
+	}
+
+	return map[string]any{
+		"fields": valuesToMap(fields),
+		"files":  files,
+	}, nil
+}
+
+// DecodeXMLBody decodes an XML document into nested maps.
+// Attributes are prefixed with "@", text content is stored under "#text"
+// and repeated elements are grouped into lists.
+func DecodeXMLBody(body []byte, _ map[string]string) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := decodeXMLElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{start.Name.Local: value}, nil
+		}
+	}
+}
+
+func decodeXMLElement(decoder *xml.Decoder, start xml.StartElement) (any, error) {
+	node := make(map[string]any)
+	for _, attr := range start.Attr {
+		node["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			addXMLChild(node, t.Name.Local, child)
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				node["#text"] = content
+			}
+			return node, nil
+		}
+	}
+}
+
+func addXMLChild(node map[string]any, name string, child any) {
+	existing, ok := node[name]
+	if !ok {
+		node[name] = child
+		return
+	}
+	if list, ok := existing.([]any); ok {
+		node[name] = append(list, child)
+		return
+	}
+	node[name] = []any{existing, child}
+}
+
+func valuesToMap(values map[string][]string) map[string]any {
+	out := make(map[string]any, len(values))
+	for k, v := range values {
+		if len(v) == 1 {
+			out[k] = v[0]
+			continue
+		}
+		list := make([]any, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		out[k] = list
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindBodyDecoder(t *testing.T) {
+	ass := assert.New(t)
+	decoders := DefaultBodyDecoders()
+
+	_, _, ok := findBodyDecoder(decoders, "application/json; charset=utf-8")
+	ass.True(ok)
+
+	_, _, ok = findBodyDecoder(decoders, "application/problem+json")
+	ass.True(ok)
+
+	_, params, ok := findBodyDecoder(decoders, "multipart/form-data; boundary=abc")
+	ass.True(ok)
+	ass.Equal("abc", params["boundary"])
+
+	_, _, ok = findBodyDecoder(decoders, "image/png")
+	ass.False(ok)
+
+	_, _, ok = findBodyDecoder(decoders, "")
+	ass.False(ok)
+}
+
+func TestDecodeFormBody(t *testing.T) {
+	ass := assert.New(t)
+
+	payload, err := DecodeFormBody([]byte("user=john&password=secret&tag=a&tag=b"), nil)
+	ass.NoError(err)
+
+	Sanitize(payload)
+	ass.Equal(map[string]any{
+		"user":     "john",
+		"password": "*****",
+		"tag":      []any{"a", "b"},
+	}, payload)
+}
+
+func TestDecodeXMLBody(t *testing.T) {
+	ass := assert.New(t)
+
+	body := `<login id="1"><user>john</user><password>secret</password><role>a</role><role>b</role></login>`
+	payload, err := DecodeXMLBody([]byte(body), nil)
+	ass.NoError(err)
+
+	Sanitize(payload)
+	ass.Equal(map[string]any{
+		"login": map[string]any{
+			"@id":      "1",
+			"user":     "john",
+			"password": "*****",
+			"role":     []any{"a", "b"},
+		},
+	}, payload)
+}
+
+func TestDecodeXMLBodyError(t *testing.T) {
+	ass := assert.New(t)
+
+	_, err := DecodeXMLBody([]byte(`<login><user>john</login>`), nil)
+	ass.Error(err)
+}
+
+func TestDecodeNDJSONBody(t *testing.T) {
+	ass := assert.New(t)
+
+	body := "{\"token\":\"abc\"}\n\n{\"name\":\"john\"}\n"
+	payload, err := DecodeNDJSONBody([]byte(body), nil)
+	ass.NoError(err)
+
+	Sanitize(payload)
+	ass.Equal([]any{
+		map[string]any{"token": "*****"},
+		map[string]any{"name": "john"},
+	}, payload)
+}
+
+func TestDecodeMultipartBodyWithoutBoundary(t *testing.T) {
+	ass := assert.New(t)
+
+	_, err := DecodeMultipartBody([]byte("--x--"), map[string]string{})
+	ass.Error(err)
+}
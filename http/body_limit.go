@@ -0,0 +1,206 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// BodyLimits bounds the size and the complexity of request bodies.
+// A zero field disables the corresponding check.
+type BodyLimits struct {
+	// MaxBytes is the maximum body size
+	MaxBytes int64
+	// MaxDepth is the maximum nesting of JSON objects and arrays
+	MaxDepth int
+	// MaxArrayLength is the maximum number of elements of a JSON array
+	MaxArrayLength int
+	// MaxKeys is the maximum number of keys of a JSON object
+	MaxKeys int
+}
+
+// DefaultBodyLimits returns the limits applied by LogJSONBodyMiddleware
+// when BodyLimitMiddleware has not set any.
+func DefaultBodyLimits() BodyLimits {
+	return BodyLimits{
+		MaxBytes:       1 << 20, // 1 MB
+		MaxDepth:       32,
+		MaxArrayLength: 10_000,
+		MaxKeys:        1_000,
+	}
+}
+
+var (
+	errBodyTooLarge = errors.New("request body too large")
+	errJSONTooDeep  = errors.New("JSON nesting too deep")
+	errJSONTooLong  = errors.New("JSON array too long")
+	errJSONTooWide  = errors.New("JSON object has too many keys")
+)
+
+type bodyLimitsKey struct{}
+
+// BodyLimitMiddleware returns an HTTP middleware rejecting oversized or overly complex bodies.
+//
+// Behavior:
+//   - Rejects bodies larger than MaxBytes with 413 (RequestEntityTooLarge payload),
+//     using Content-Length upfront when it is known.
+//   - For JSON bodies (application/json, "+json" suffix types and NDJSON), walks the
+//     tokens without building values and rejects with 400 (BadRequest payload) invalid JSON
+//     or documents exceeding MaxDepth, MaxArrayLength or MaxKeys.
+//   - Restores the request body so the next handler can consume it.
+//   - Stores the limits in the request context so that LogJSONBodyMiddleware applies them too.
+//
+// Register it per route (see Group) to get different limits per route.
+//
+// Example usage:
+//
+//	limits := DefaultBodyLimits()
+//	limits.MaxBytes = 10 << 20
+//	http.Handle("/upload", BodyLimitMiddleware(limits)(LogJSONBodyMiddleware(logger)(myHandler)))
+func BodyLimitMiddleware(limits BodyLimits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), bodyLimitsKey{}, limits))
+
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if limits.MaxBytes > 0 && r.ContentLength > limits.MaxBytes {
+				respondWithJSON(w, RequestEntityTooLarge(fmt.Sprintf("body exceeds %d bytes", limits.MaxBytes)))
+				return
+			}
+
+			body, err := readLimitedBody(r.Body, limits.MaxBytes)
+			if errors.Is(err, errBodyTooLarge) {
+				respondWithJSON(w, RequestEntityTooLarge(fmt.Sprintf("body exceeds %d bytes", limits.MaxBytes)))
+				return
+			}
+			// Limit set upstream, e.g. by DecompressMiddleware
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				respondWithJSON(w, RequestEntityTooLarge(fmt.Sprintf("body exceeds %d bytes", maxBytesErr.Limit)))
+				return
+			}
+			if err != nil {
+				respondWithJSON(w, BadRequest("unreadable body"))
+				return
+			}
+			r.Body = readCloser{Reader: bytes.NewReader(body), Closer: r.Body}
+
+			if isJSONContentType(r.Header.Get("Content-Type")) && len(bytes.TrimSpace(body)) > 0 {
+				if err := checkJSONLimits(body, limits); err != nil {
+					respondWithJSON(w, BadRequest(err.Error()))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bodyLimitsFromContext returns the limits set by BodyLimitMiddleware, or DefaultBodyLimits.
+func bodyLimitsFromContext(ctx context.Context) BodyLimits {
+	if limits, ok := ctx.Value(bodyLimitsKey{}).(BodyLimits); ok {
+		return limits
+	}
+	return DefaultBodyLimits()
+}
+
+// readLimitedBody reads the whole body, failing with errBodyTooLarge past maxBytes (if > 0).
+func readLimitedBody(body io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return data, err
+	}
+	if int64(len(data)) > maxBytes {
+		return data, errBodyTooLarge
+	}
+	return data, nil
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" ||
+		mediaType == "application/x-ndjson" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonFrame tracks an open JSON object or array while walking tokens.
+type jsonFrame struct {
+	object bool
+	tokens int
+}
+
+// checkJSONLimits walks the JSON tokens of body (one or several concatenated documents)
+// and checks them against limits without decoding values.
+func checkJSONLimits(body []byte, limits BodyLimits) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	var stack []jsonFrame
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return fmt.Errorf("invalid JSON body: %w", io.ErrUnexpectedEOF)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON body: %w", err)
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			if err := countJSONToken(stack, limits); err != nil {
+				return err
+			}
+			stack = append(stack, jsonFrame{object: tok == json.Delim('{')})
+			if limits.MaxDepth > 0 && len(stack) > limits.MaxDepth {
+				return fmt.Errorf("%w: max depth is %d", errJSONTooDeep, limits.MaxDepth)
+			}
+
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+
+		default:
+			if err := countJSONToken(stack, limits); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// countJSONToken counts a token inside the innermost container.
+// Object tokens alternate between keys and values, so odd tokens are keys.
+func countJSONToken(stack []jsonFrame, limits BodyLimits) error {
+	if len(stack) == 0 {
+		return nil
+	}
+	top := &stack[len(stack)-1]
+	top.tokens++
+
+	if top.object {
+		if limits.MaxKeys > 0 && top.tokens%2 == 1 && (top.tokens+1)/2 > limits.MaxKeys {
+			return fmt.Errorf("%w: max keys is %d", errJSONTooWide, limits.MaxKeys)
+		}
+		return nil
+	}
+	if limits.MaxArrayLength > 0 && top.tokens > limits.MaxArrayLength {
+		return fmt.Errorf("%w: max length is %d", errJSONTooLong, limits.MaxArrayLength)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckJSONLimits(t *testing.T) {
+	ass := assert.New(t)
+	limits := BodyLimits{MaxDepth: 2, MaxArrayLength: 3, MaxKeys: 2}
+
+	ass.NoError(checkJSONLimits([]byte(`{"a":[1,2,3],"b":{"c":1}}`), limits))
+	ass.ErrorIs(checkJSONLimits([]byte(`{"a":{"b":{"c":1}}}`), limits), errJSONTooDeep)
+	ass.ErrorIs(checkJSONLimits([]byte(`[1,2,3,4]`), limits), errJSONTooLong)
+	ass.ErrorIs(checkJSONLimits([]byte(`{"a":1,"b":2,"c":3}`), limits), errJSONTooWide)
+	// Nested containers count as a single value of their parent
+	ass.NoError(checkJSONLimits([]byte(`[{"x":1,"y":2},[1,2,3],4]`), limits))
+	ass.Error(checkJSONLimits([]byte(`{"a":`), limits))
+	// Zero limits disable the checks
+	ass.NoError(checkJSONLimits([]byte(`[[[[[1,2,3,4,5]]]]]`), BodyLimits{}))
+}
+
+func TestBodyLimitRejectsLargeBody(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := BodyLimitMiddleware(BodyLimits{MaxBytes: 4})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Known Content-Length
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("too large"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+
+	// Unknown Content-Length
+	req = httptest.NewRequest(http.MethodPost, "/", io.MultiReader(strings.NewReader("too large")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("request entity too large", body["message"])
+	ass.Equal("body exceeds 4 bytes", body["details"])
+}
+
+func TestBodyLimitRejectsComplexJSON(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := BodyLimitMiddleware(BodyLimits{MaxDepth: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":{"b":1}}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusBadRequest, rec.Code)
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("bad request", body["message"])
+	ass.Equal("JSON nesting too deep: max depth is 1", body["details"])
+}
+
+func TestBodyLimitRestoresBody(t *testing.T) {
+	ass := assert.New(t)
+
+	payload := `{"a":[1,2]}`
+	handler := BodyLimitMiddleware(DefaultBodyLimits())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, err := io.ReadAll(r.Body)
+		ass.NoError(err)
+		ass.Equal(payload, string(read))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusOK, rec.Code)
+}
+
+func TestLoggingRespectsBodyLimits(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelDebug)
+
+	payload := `{"password":"secret","nested":{"a":{"b":1}}}`
+	limits := BodyLimits{MaxBytes: 8}
+	logged := LogJSONBodyMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, err := io.ReadAll(r.Body)
+		ass.NoError(err)
+		ass.Equal(payload, string(read))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// The limits are only known by the logging middleware, the handler still gets the whole body
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), bodyLimitsKey{}, limits))
+		logged.ServeHTTP(w, r)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Contains(buf.String(), "request body too large to be logged")
+	ass.NotContains(buf.String(), "secret")
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CacheConfig configures CacheMiddleware.
+type CacheConfig struct {
+	// CacheControl is set on responses that do not define their own, e.g. "public, max-age=60"
+	CacheControl string
+	// Store enables the shared cache (default: none, only ETags and conditional requests)
+	Store ResponseCache
+	// TTL of the responses kept in Store (default: 1 minute)
+	TTL time.Duration
+	// Key identifies a response in Store (default: method, request URI and Accept header).
+	// A custom Key must include the Accept header on routes that negotiate their representation.
+	Key func(r *http.Request) string
+	// Tags labels a response in Store, so that it can be dropped with ResponseCache.InvalidateTags
+	Tags func(r *http.Request) []string
+}
+
+// CachedResponse is a response kept in a ResponseCache.
+type CachedResponse struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// CacheMiddleware returns an HTTP middleware caching GET and HEAD responses.
+//
+// Behavior:
+//   - Buffers 200 responses and sets a strong ETag computed from the body,
+//     unless the handler set its own.
+//   - Answers If-None-Match (or If-Modified-Since when the response has a Last-Modified header)
+//     with 304 and no body.
+//   - Sets CacheConfig.CacheControl when the handler did not set Cache-Control.
+//   - With a Store, serves responses from the shared cache ("X-Cache: HIT") during TTL.
+//     Only GET responses are stored, HEAD requests are answered from them.
+//     Requests with an Authorization or Cookie header and responses with Set-Cookie,
+//     "Cache-Control: no-store/private" or a Vary header naming another header than Accept
+//     are never shared.
+//     ⚠️ Responses are buffered: do not use it on streamed routes.
+//     ⚠️ Register it inside CompressMiddleware so that ETags are computed on plain bodies.
+//
+// Example usage:
+//
+//	store := NewMemoryResponseCache()
+//	cache := CacheMiddleware(logger, CacheConfig{
+//		CacheControl: "public, max-age=60",
+//		Store:        store,
+//		Tags:         func(r *http.Request) []string { return []string{"products"} },
+//	})
+//	mux.Handle("GET /products", cache(JSON(listProducts)))
+//	// after an update
+//	store.InvalidateTags(ctx, "products")
+func CacheMiddleware(logger *slog.Logger, cfg CacheConfig) func(http.Handler) http.Handler {
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	key := cfg.Key
+	if key == nil {
+		key = func(r *http.Request) string {
+			// HEAD is answered from the GET response, Accept selects its representation (Vary: Accept)
+			return http.MethodGet + " " + r.URL.RequestURI() + " " + strings.Join(r.Header.Values("Accept"), ", ")
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Credentials make the response specific to a user
+			shared := cfg.Store != nil && r.Header.Get("Authorization") == "" && r.Header.Get("Cookie") == ""
+			if shared {
+				cached, ok, err := cfg.Store.Get(r.Context(), key(r))
+				if err != nil {
+					loggerWithRequestID(logger, r).Warn("response cache error", slog.Any("error", err))
+				}
+				if ok {
+					w.Header().Set("X-Cache", "HIT")
+					writeCachedResponse(w, r, cached)
+					return
+				}
+				w.Header().Set("X-Cache", "MISS")
+			}
+
+			bw := &bufferedWriter{header: make(http.Header), status: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			resp := &CachedResponse{Status: bw.status, Header: bw.header, Body: bw.body.Bytes(), StoredAt: time.Now()}
+			if resp.Status == http.StatusOK {
+				if resp.Header.Get("ETag") == "" {
+					resp.Header.Set("ETag", strongETag(resp.Body))
+				}
+				if resp.Header.Get("Cache-Control") == "" && cfg.CacheControl != "" {
+					resp.Header.Set("Cache-Control", cfg.CacheControl)
+				}
+				// HEAD handlers often write no body, it must not be served to GET requests
+				if shared && r.Method == http.MethodGet && isShareable(resp.Header) {
+					var tags []string
+					if cfg.Tags != nil {
+						tags = cfg.Tags(r)
+					}
+					if err := cfg.Store.Set(r.Context(), key(r), resp, ttl, tags...); err != nil {
+						loggerWithRequestID(logger, r).Warn("response cache error", slog.Any("error", err))
+					}
+				}
+			}
+
+			writeCachedResponse(w, r, resp)
+		})
+	}
+}
+
+// bufferedWriter keeps the whole response in memory.
+type bufferedWriter struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if bw.wroteHeader {
+		return
+	}
+	bw.status = code
+	bw.wroteHeader = true
+}
+
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	bw.WriteHeader(http.StatusOK)
+	return bw.body.Write(b)
+}
+
+// writeCachedResponse writes resp, or a 304 when the request preconditions match it.
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, resp *CachedResponse) {
+	mergeHeader(w.Header(), resp.Header)
+
+	if resp.Status == http.StatusOK && notModified(r, resp.Header) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(resp.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+}
+
+// mergeHeader copies the values of src (shared by every response served from the cache) into dst.
+// Vary is merged, and the CORS headers already set by an outer CORSMiddleware are kept.
+func mergeHeader(dst, src http.Header) {
+	for k, v := range src {
+		switch {
+		case k == "Vary":
+			addVary(dst, v...)
+		case strings.HasPrefix(k, "Access-Control-") && len(dst[k]) > 0:
+		default:
+			dst[k] = slices.Clone(v)
+		}
+	}
+}
+
+// addVary adds the header names missing from the Vary header.
+func addVary(header http.Header, values ...string) {
+	present := make(map[string]bool)
+	for _, name := range parseHeaderList(header.Values("Vary")) {
+		present[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range parseHeaderList(values) {
+		if !present[http.CanonicalHeaderKey(name)] {
+			present[http.CanonicalHeaderKey(name)] = true
+			header.Add("Vary", name)
+		}
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since in its absence (RFC 9110 §13.2.2).
+func notModified(r *http.Request, header http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, header.Get("ETag"))
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	lastModified := header.Get("Last-Modified")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// etagMatches implements the weak comparison used by If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// strongETag derives an ETag from the body bytes.
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+func isShareable(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	// Only Accept is part of the default key
+	for _, name := range parseHeaderList(header.Values("Vary")) {
+		if http.CanonicalHeaderKey(name) != "Accept" {
+			return false
+		}
+	}
+	cc := strings.ToLower(header.Get("Cache-Control"))
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "private")
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// ResponseCache is the shared cache used by CacheMiddleware.
+type ResponseCache interface {
+	// Get returns the response stored for key, if any and not expired
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+	// Set stores resp for ttl, labelled with tags
+	Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration, tags ...string) error
+	// InvalidateTags drops every response labelled with one of tags
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+// MemoryResponseCache is an in-process ResponseCache, suited for a single instance.
+type MemoryResponseCache struct {
+	mu        sync.Mutex
+	entries   map[string]memoryCacheEntry
+	tags      map[string]map[string]struct{}
+	lastSweep time.Time
+}
+
+type memoryCacheEntry struct {
+	resp    *CachedResponse
+	tags    []string
+	expires time.Time
+}
+
+// NewMemoryResponseCache creates an empty in-memory cache.
+func NewMemoryResponseCache() *MemoryResponseCache {
+	return &MemoryResponseCache{
+		entries:   make(map[string]memoryCacheEntry),
+		tags:      make(map[string]map[string]struct{}),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get implements ResponseCache.
+func (c *MemoryResponseCache) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !time.Now().Before(entry.expires) {
+		return nil, false, nil
+	}
+	return entry.resp, true, nil
+}
+
+// Set implements ResponseCache.
+func (c *MemoryResponseCache) Set(_ context.Context, key string, resp *CachedResponse, ttl time.Duration, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) > memorySweepInterval {
+		c.sweep(now)
+	}
+
+	c.remove(key)
+	c.entries[key] = memoryCacheEntry{resp: resp, tags: tags, expires: now.Add(ttl)}
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+	return nil
+}
+
+// InvalidateTags implements ResponseCache.
+func (c *MemoryResponseCache) InvalidateTags(_ context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.remove(key)
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+func (c *MemoryResponseCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	delete(c.entries, key)
+}
+
+func (c *MemoryResponseCache) sweep(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			c.remove(key)
+		}
+	}
+	c.lastSweep = now
+}
+
+const (
+	badgerCachePrefix    = "cache:entry:"
+	badgerCacheTagPrefix = "cache:tag:"
+)
+
+// BadgerResponseCache is a ResponseCache persisted in Badger, shared by the processes using the same database.
+// Entries and tag indexes expire with the Badger TTL.
+type BadgerResponseCache struct {
+	db *badger.DB
+}
+
+// NewBadgerResponseCache creates a cache on an opened database (see database.LoadBadger).
+func NewBadgerResponseCache(db *badger.DB) *BadgerResponseCache {
+	return &BadgerResponseCache{db: db}
+}
+
+// Get implements ResponseCache.
+func (c *BadgerResponseCache) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	var resp *CachedResponse
+	err := c.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(badgerCachePrefix + key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			resp = &CachedResponse{}
+			return json.Unmarshal(v, resp)
+		})
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return resp, resp != nil, nil
+}
+
+// Set implements ResponseCache. Each tag is indexed by a "cache:tag:<len(tag)>:<tag>:<key>" entry,
+// the length keeps a tag from matching the index of a longer one ("product" and "product:42").
+func (c *BadgerResponseCache) Set(_ context.Context, key string, resp *CachedResponse, ttl time.Duration, tags ...string) error {
+	value, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return c.db.Update(func(txn *badger.Txn) error {
+		if err := txn.SetEntry(badger.NewEntry([]byte(badgerCachePrefix+key), value).WithTTL(ttl)); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			index := badgerTagIndexPrefix(tag) + key
+			if err := txn.SetEntry(badger.NewEntry([]byte(index), nil).WithTTL(ttl)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InvalidateTags implements ResponseCache. Deletes are batched, a tag may label more entries
+// than a single transaction holds.
+func (c *BadgerResponseCache) InvalidateTags(_ context.Context, tags ...string) error {
+	wb := c.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, tag := range tags {
+		prefix := []byte(badgerTagIndexPrefix(tag))
+
+		var indexes [][]byte
+		err := c.db.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+			defer it.Close()
+			for it.Rewind(); it.Valid(); it.Next() {
+				indexes = append(indexes, it.Item().KeyCopy(nil))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, index := range indexes {
+			key := index[len(prefix):]
+			if err := wb.Delete(append([]byte(badgerCachePrefix), key...)); err != nil {
+				return err
+			}
+			if err := wb.Delete(index); err != nil {
+				return err
+			}
+		}
+	}
+	return wb.Flush()
+}
+
+func badgerTagIndexPrefix(tag string) string {
+	return badgerCacheTagPrefix + strconv.Itoa(len(tag)) + ":" + tag + ":"
+}
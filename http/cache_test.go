@@ -0,0 +1,268 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagAndIfNoneMatch(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CacheMiddleware(nil, CacheConfig{CacheControl: "public, max-age=60"})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(map[string]string{"hello": "world"})
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	etag := rec.Header().Get("ETag")
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Regexp(`^"[A-Za-z0-9_-]+"$`, etag)
+	ass.Equal("public, max-age=60", rec.Header().Get("Cache-Control"))
+	ass.JSONEq(`{"hello":"world"}`, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusNotModified, rec.Code)
+	ass.Equal(etag, rec.Header().Get("ETag"))
+	ass.Equal(0, rec.Body.Len())
+
+	req.Header.Set("If-None-Match", `"other"`)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal(http.StatusOK, rec.Code)
+}
+
+func TestIfModifiedSince(t *testing.T) {
+	ass := assert.New(t)
+
+	lastModified := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := CacheMiddleware(nil, CacheConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK("ok").AddHeader("Last-Modified", lastModified.Format(http.TimeFormat))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-Modified-Since", lastModified.Add(time.Hour).Format(http.TimeFormat))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal(http.StatusNotModified, rec.Code)
+
+	req.Header.Set("If-Modified-Since", lastModified.Add(-time.Hour).Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal(http.StatusOK, rec.Code)
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CacheMiddleware(nil, CacheConfig{CacheControl: "public, max-age=60"})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return NotFound("missing")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ass.Equal(http.StatusNotFound, rec.Code)
+	ass.Empty(rec.Header().Get("ETag"))
+	ass.Empty(rec.Header().Get("Cache-Control"))
+}
+
+func testSharedCache(t *testing.T, store ResponseCache) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	handler := CacheMiddleware(nil, CacheConfig{
+		Store: store,
+		Tags:  func(r *http.Request) []string { return []string{"products"} },
+	})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(calls.Add(1))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+	ass.Equal("MISS", rec.Header().Get("X-Cache"))
+	ass.Equal("1\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+	ass.Equal("HIT", rec.Header().Get("X-Cache"))
+	ass.Equal("1\n", rec.Body.String())
+	ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	// Authorized requests bypass the shared cache
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal("2\n", rec.Body.String())
+
+	// So do requests authenticated with a cookie
+	req = httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.Header.Set("Cookie", "session=alice")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal("3\n", rec.Body.String())
+	ass.Empty(rec.Header().Get("X-Cache"))
+
+	ass.NoError(store.InvalidateTags(t.Context(), "products"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products", nil))
+	ass.Equal("MISS", rec.Header().Get("X-Cache"))
+	ass.Equal("4\n", rec.Body.String())
+}
+
+// testNestedTags checks that a tag never matches the entries of a longer tag sharing its prefix.
+func testNestedTags(t *testing.T, store ResponseCache) {
+	ass := assert.New(t)
+	ctx := t.Context()
+
+	ass.NoError(store.Set(ctx, "GET /products", &CachedResponse{Status: http.StatusOK}, time.Minute, "product"))
+	ass.NoError(store.Set(ctx, "GET /products/42", &CachedResponse{Status: http.StatusOK}, time.Minute, "product:42"))
+
+	ass.NoError(store.InvalidateTags(ctx, "product"))
+	_, ok, err := store.Get(ctx, "GET /products")
+	ass.NoError(err)
+	ass.False(ok)
+	_, ok, err = store.Get(ctx, "GET /products/42")
+	ass.NoError(err)
+	ass.True(ok)
+
+	ass.NoError(store.InvalidateTags(ctx, "product:42"))
+	_, ok, err = store.Get(ctx, "GET /products/42")
+	ass.NoError(err)
+	ass.False(ok)
+}
+
+func TestMemoryResponseCache(t *testing.T) {
+	testSharedCache(t, NewMemoryResponseCache())
+	testNestedTags(t, NewMemoryResponseCache())
+}
+
+func TestBadgerResponseCache(t *testing.T) {
+	testSharedCache(t, NewBadgerResponseCache(openTestBadger(t)))
+	testNestedTags(t, NewBadgerResponseCache(openTestBadger(t)))
+}
+
+func TestMemoryResponseCacheExpiry(t *testing.T) {
+	ass := assert.New(t)
+
+	store := NewMemoryResponseCache()
+	ass.NoError(store.Set(t.Context(), "k", &CachedResponse{Status: http.StatusOK}, -time.Second))
+
+	_, ok, err := store.Get(t.Context(), "k")
+	ass.NoError(err)
+	ass.False(ok)
+}
+
+func TestHeadDoesNotPopulateSharedCache(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CacheMiddleware(nil, CacheConfig{Store: NewMemoryResponseCache()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		_, _ = w.Write([]byte("full body"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/file", nil))
+	ass.Equal("MISS", rec.Header().Get("X-Cache"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/file", nil))
+	ass.Equal("MISS", rec.Header().Get("X-Cache"))
+	ass.Equal("full body", rec.Body.String())
+
+	// HEAD is answered from the stored GET response
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/file", nil))
+	ass.Equal("HIT", rec.Header().Get("X-Cache"))
+	ass.Empty(rec.Body.String())
+}
+
+func TestCachedHeadersAreCopiedAndMerged(t *testing.T) {
+	ass := assert.New(t)
+
+	cache := CacheMiddleware(nil, CacheConfig{Store: NewMemoryResponseCache()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept")
+		w.Header().Set("X-Tags", "a")
+		_, _ = w.Write([]byte("body"))
+	}))
+	handler := CORSMiddleware(CORSConfig{Policies: []CORSPolicy{{AllowedOrigins: []string{"https://app.example.com"}}}})(cache)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		ass.Equal([]string{"a"}, rec.Header().Values("X-Tags"))
+		ass.Equal([]string{"Origin", "Accept"}, rec.Header().Values("Vary"))
+		ass.Equal("https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+
+		// Must not leak into the stored response
+		rec.Header().Add("X-Tags", "b")
+	}
+}
+
+func TestSharedCacheKeepsNegotiatedRepresentationsApart(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	handler := CacheMiddleware(nil, CacheConfig{Store: NewMemoryResponseCache()})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		calls.Add(1)
+		return OK(encodedItem{ID: 1, Name: "one"})
+	}))
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("application/xml")
+	ass.Equal("MISS", rec.Header().Get("X-Cache"))
+	ass.Equal("application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	// An XML client must not poison the JSON entry
+	rec = get("application/json")
+	ass.Equal("MISS", rec.Header().Get("X-Cache"))
+	ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.Contains(rec.Body.String(), `"name":"one"`)
+
+	rec = get("application/json")
+	ass.Equal("HIT", rec.Header().Get("X-Cache"))
+	ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.Equal([]string{"Accept"}, rec.Header().Values("Vary"))
+	ass.Equal(int32(2), calls.Load())
+}
+
+func TestResponsesVaryingOnOtherHeadersAreNotShared(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CacheMiddleware(nil, CacheConfig{Store: NewMemoryResponseCache()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	for _, lang := range []string{"fr", "en"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		ass.Equal("MISS", rec.Header().Get("X-Cache"))
+		ass.Equal(lang, rec.Body.String())
+	}
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps an http.Handler, as returned by LogJSONBodyMiddleware or RequestIDMiddleware.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is an ordered list of middlewares: the first one is the outermost.
+//
+// Example usage:
+//
+//	chain := NewChain(RequestIDMiddleware(RequestIDConfig{}), LogJSONBodyMiddleware(logger))
+//	http.Handle("/api", chain.Then(myHandler))
+type Chain struct {
+	middlewares []Middleware
+}
+
+// NewChain creates a chain from the given middlewares.
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain{middlewares: append([]Middleware(nil), middlewares...)}
+}
+
+// Use adds middlewares at the end of the chain, in place.
+func (c *Chain) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Append returns a new chain with the given middlewares added at the end,
+// the original chain is left untouched.
+func (c Chain) Append(middlewares ...Middleware) Chain {
+	all := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
+	all = append(all, c.middlewares...)
+	all = append(all, middlewares...)
+	return Chain{middlewares: all}
+}
+
+// Then wraps h with every middleware of the chain.
+// A nil handler falls back to http.DefaultServeMux.
+func (c Chain) Then(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
+
+// ThenFunc is Then for an http.HandlerFunc.
+func (c Chain) ThenFunc(fn http.HandlerFunc) http.Handler {
+	if fn == nil {
+		return c.Then(nil)
+	}
+	return c.Then(fn)
+}
+
+// Group registers routes on an http.ServeMux under a common path prefix,
+// each route being wrapped by the group middlewares.
+// Patterns follow the Go 1.22 ServeMux syntax ("[METHOD ]/path/{wildcard}").
+//
+// Example usage:
+//
+//	mux := http.NewServeMux()
+//	api := NewGroup(mux, "/api", RequestIDMiddleware(RequestIDConfig{}))
+//	api.JSON("GET /users/{id}", getUser)
+//
+//	admin := api.Group("/admin", authMiddleware)
+//	admin.JSON("DELETE /users/{id}", deleteUser) // DELETE /api/admin/users/{id}
+type Group struct {
+	mux    *http.ServeMux
+	prefix string
+	chain  Chain
+}
+
+// NewGroup creates a group registering its routes on mux under prefix ("" for the root).
+func NewGroup(mux *http.ServeMux, prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		mux:    mux,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		chain:  NewChain(middlewares...),
+	}
+}
+
+// Use adds middlewares to the group. Only routes registered afterwards are affected.
+func (g *Group) Use(middlewares ...Middleware) {
+	g.chain.Use(middlewares...)
+}
+
+// Group creates a sub-group inheriting the prefix and middlewares of g.
+func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
+	return &Group{
+		mux:    g.mux,
+		prefix: g.prefix + strings.TrimSuffix(prefix, "/"),
+		chain:  g.chain.Append(middlewares...),
+	}
+}
+
+// Handle registers h for the pattern, relative to the group prefix.
+func (g *Group) Handle(pattern string, h http.Handler) {
+	g.mux.Handle(g.pattern(pattern), g.chain.Then(h))
+}
+
+// HandleFunc registers fn for the pattern, relative to the group prefix.
+func (g *Group) HandleFunc(pattern string, fn http.HandlerFunc) {
+	g.Handle(pattern, fn)
+}
+
+// JSON registers a Handler rendered with JSON.
+func (g *Group) JSON(pattern string, h Handler) {
+	g.Handle(pattern, JSON(h))
+}
+
+// Stream registers a Handler rendered with Stream.
+func (g *Group) Stream(pattern string, h Handler) {
+	g.Handle(pattern, Stream(h))
+}
+
+// pattern prefixes the path of a ServeMux pattern, keeping its optional method.
+func (g *Group) pattern(pattern string) string {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = "", pattern
+	}
+	path = strings.TrimLeft(path, " \t")
+
+	if g.prefix != "" && !strings.HasPrefix(path, "/") {
+		// Same behavior as http.ServeMux on invalid patterns
+		panic(fmt.Sprintf("http: pattern %q must start with a path to be used in group %q", pattern, g.prefix))
+	}
+
+	if method == "" {
+		return g.prefix + path
+	}
+	return method + " " + g.prefix + path
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tagMiddleware(tag string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", tag)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	ass := assert.New(t)
+
+	chain := NewChain(tagMiddleware("a"), tagMiddleware("b"))
+	chain.Use(tagMiddleware("c"))
+
+	rec := httptest.NewRecorder()
+	chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Trace", "handler")
+	}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ass.Equal([]string{"a", "b", "c", "handler"}, rec.Header().Values("X-Trace"))
+}
+
+func TestChainAppendDoesNotModifyOriginal(t *testing.T) {
+	ass := assert.New(t)
+
+	base := NewChain(tagMiddleware("a"))
+	extended := base.Append(tagMiddleware("b"))
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	rec := httptest.NewRecorder()
+	base.Then(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Equal([]string{"a"}, rec.Header().Values("X-Trace"))
+
+	rec = httptest.NewRecorder()
+	extended.Then(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Equal([]string{"a", "b"}, rec.Header().Values("X-Trace"))
+}
+
+func TestGroupRoutes(t *testing.T) {
+	ass := assert.New(t)
+
+	mux := http.NewServeMux()
+	api := NewGroup(mux, "/api", tagMiddleware("api"))
+	api.JSON("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(map[string]string{"id": r.PathValue("id")})
+	})
+
+	admin := api.Group("/admin/", tagMiddleware("admin"))
+	admin.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/42", nil))
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.JSONEq(`{"id":"42"}`, rec.Body.String())
+	ass.Equal([]string{"api"}, rec.Header().Values("X-Trace"))
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/admin/users/42", nil))
+	ass.Equal(http.StatusNoContent, rec.Code)
+	ass.Equal([]string{"api", "admin"}, rec.Header().Values("X-Trace"))
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/users/42", nil))
+	ass.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestGroupRejectsHostPattern(t *testing.T) {
+	ass := assert.New(t)
+
+	group := NewGroup(http.NewServeMux(), "/api")
+	ass.Panics(func() {
+		group.HandleFunc("example.com/users", func(w http.ResponseWriter, r *http.Request) {})
+	})
+}
@@ -0,0 +1,436 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mama165/sdk-go/breaker"
+)
+
+// RetryConfig configures the retries of a client built with ClientBuilder.
+type RetryConfig struct {
+	// MaxAttempts including the first one (default: 3)
+	MaxAttempts int
+	// BaseDelay is the backoff of the first retry, doubled on each attempt (default: 100ms)
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay stops the retries (default: 5s)
+	MaxDelay time.Duration
+	// RetryOn decides whether an attempt is retried
+	// (default: network errors and 429, 502, 503, 504 responses)
+	RetryOn func(resp *http.Response, err error) bool
+	// IdempotencyHeader makes non idempotent methods retryable when present (default: Idempotency-Key)
+	IdempotencyHeader string
+}
+
+// RoundTripperFunc adapts a function to http.RoundTripper.
+type RoundTripperFunc func(r *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// ClientBuilder builds an http.Client for outgoing calls.
+//
+// Behavior:
+//   - Forwards the request ID and trace context of the request context (see RequestIDTransport).
+//   - WithRetry: retries with exponential backoff and full jitter, honoring Retry-After.
+//     Only idempotent methods (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) and requests carrying
+//     an Idempotency-Key header are retried, bodies are replayed with Request.GetBody.
+//   - WithAttemptTimeout: bounds each attempt, including the read of the response body,
+//     while WithTimeout bounds the whole call with its retries.
+//   - WithLogging: logs every attempt (method, host, path, status, duration) at INFO level,
+//     and the sanitized request and response bodies at DEBUG level (up to maxObservedBytes).
+//   - Use: adds a RoundTripper middleware (e.g. a circuit breaker) called on every attempt.
+//
+// The RoundTripper chain, outermost first: request ID, retry, Use middlewares, logging,
+// attempt timeout, base transport.
+//
+// Example usage:
+//
+//	client := NewClient().
+//		WithTimeout(10 * time.Second).
+//		WithAttemptTimeout(2 * time.Second).
+//		WithRetry(RetryConfig{MaxAttempts: 4}).
+//		WithLogging(logger).
+//		Build()
+//	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+//	resp, err := client.Do(req)
+type ClientBuilder struct {
+	base            http.RoundTripper
+	timeout         time.Duration
+	attemptTimeout  time.Duration
+	retry           *RetryConfig
+	logger          *slog.Logger
+	decoders        map[string]BodyDecoder
+	requestIDHeader string
+	middlewares     []func(http.RoundTripper) http.RoundTripper
+}
+
+// NewClient starts a builder over http.DefaultTransport, without retries or timeouts.
+func NewClient() *ClientBuilder {
+	return &ClientBuilder{base: http.DefaultTransport}
+}
+
+// WithTransport replaces the base transport.
+func (b *ClientBuilder) WithTransport(rt http.RoundTripper) *ClientBuilder {
+	b.base = rt
+	return b
+}
+
+// WithTimeout bounds the whole call, retries included (http.Client.Timeout).
+func (b *ClientBuilder) WithTimeout(d time.Duration) *ClientBuilder {
+	b.timeout = d
+	return b
+}
+
+// WithAttemptTimeout bounds each attempt.
+func (b *ClientBuilder) WithAttemptTimeout(d time.Duration) *ClientBuilder {
+	b.attemptTimeout = d
+	return b
+}
+
+// WithRetry enables retries, the zero RetryConfig uses the defaults.
+func (b *ClientBuilder) WithRetry(cfg RetryConfig) *ClientBuilder {
+	b.retry = &cfg
+	return b
+}
+
+// WithLogging logs every attempt, decoding DEBUG bodies with DefaultBodyDecoders.
+func (b *ClientBuilder) WithLogging(logger *slog.Logger) *ClientBuilder {
+	return b.WithLoggingDecoders(logger, DefaultBodyDecoders())
+}
+
+// WithLoggingDecoders behaves like WithLogging with a custom set of decoders keyed by media type.
+func (b *ClientBuilder) WithLoggingDecoders(logger *slog.Logger, decoders map[string]BodyDecoder) *ClientBuilder {
+	b.logger = logger
+	b.decoders = decoders
+	return b
+}
+
+// WithRequestIDHeader changes the header carrying the request ID (default: X-Request-ID).
+func (b *ClientBuilder) WithRequestIDHeader(header string) *ClientBuilder {
+	b.requestIDHeader = header
+	return b
+}
+
+// Use adds RoundTripper middlewares, called on every attempt in the order given.
+func (b *ClientBuilder) Use(mws ...func(http.RoundTripper) http.RoundTripper) *ClientBuilder {
+	b.middlewares = append(b.middlewares, mws...)
+	return b
+}
+
+// Build returns the client. The builder can be reused.
+func (b *ClientBuilder) Build() *http.Client {
+	rt := b.base
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if b.attemptTimeout > 0 {
+		rt = &attemptTimeoutTransport{base: rt, timeout: b.attemptTimeout}
+	}
+	if b.logger != nil {
+		rt = &loggingTransport{base: rt, logger: b.logger, decoders: b.decoders}
+	}
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		rt = b.middlewares[i](rt)
+	}
+	if b.retry != nil {
+		rt = newRetryTransport(rt, *b.retry, b.logger)
+	}
+	rt = &RequestIDTransport{Base: rt, Header: b.requestIDHeader}
+
+	return &http.Client{Transport: rt, Timeout: b.timeout}
+}
+
+// retryTransport replays retryable requests.
+type retryTransport struct {
+	base   http.RoundTripper
+	cfg    RetryConfig
+	logger *slog.Logger
+}
+
+func newRetryTransport(base http.RoundTripper, cfg RetryConfig, logger *slog.Logger) *retryTransport {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = 100 * time.Millisecond
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = 5 * time.Second
+	}
+	if cfg.RetryOn == nil {
+		cfg.RetryOn = DefaultRetryOn
+	}
+	if cfg.IdempotencyHeader == "" {
+		cfg.IdempotencyHeader = DefaultIdempotencyHeader
+	}
+	return &retryTransport{base: base, cfg: cfg, logger: logger}
+}
+
+// DefaultRetryOn retries network errors and 429, 502, 503 and 504 responses.
+// Calls rejected by an open circuit (breaker.ErrOpen) are not retried.
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, breaker.ErrOpen)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !t.retryable(r) {
+		return t.base.RoundTrip(r)
+	}
+
+	ctx := r.Context()
+	for attempt := 1; ; attempt++ {
+		req := r
+		if attempt > 1 {
+			// A RoundTripper must not modify the original request
+			req = r.Clone(ctx)
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.cfg.MaxAttempts || ctx.Err() != nil || !t.cfg.RetryOn(resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > t.cfg.MaxDelay {
+					// The server asks for more than we are willing to wait
+					return resp, nil
+				}
+				delay = retryAfter
+			}
+			// Drain the body so that the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxObservedBytes))
+			_ = resp.Body.Close()
+		}
+
+		if t.logger != nil {
+			attrs := []any{
+				slog.String("method", r.Method),
+				slog.String("host", r.URL.Host),
+				slog.String("path", r.URL.Path),
+				slog.Int("attempt", attempt),
+				slog.Duration("delay", delay),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.Any("error", err))
+			} else {
+				attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			}
+			loggerWithRequestID(t.logger, r).Warn("retrying outgoing request", attrs...)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether r is idempotent and its body can be replayed.
+func (t *retryTransport) retryable(r *http.Request) bool {
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return r.Header.Get(t.cfg.IdempotencyHeader) != ""
+	}
+}
+
+// backoff returns a random delay in (0, min(MaxDelay, BaseDelay*2^(attempt-1))] ("full jitter").
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	ceiling := t.cfg.MaxDelay
+	if shift := attempt - 1; shift < 32 && t.cfg.BaseDelay<<shift > 0 && t.cfg.BaseDelay<<shift < ceiling {
+		ceiling = t.cfg.BaseDelay << shift
+	}
+	return time.Duration(rand.Int64N(int64(ceiling))) + 1
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(at.Sub(now), 0), true
+}
+
+// attemptTimeoutTransport bounds an attempt until its response body is closed.
+type attemptTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *attemptTimeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(r.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the attempt context with the response body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// loggingTransport logs every attempt.
+type loggingTransport struct {
+	base     http.RoundTripper
+	logger   *slog.Logger
+	decoders map[string]BodyDecoder
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *loggingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	log := loggerWithRequestID(t.logger, r)
+	debug := log.Enabled(r.Context(), slog.LevelDebug)
+
+	if debug {
+		t.logRequestBody(log, r)
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(r)
+	duration := time.Since(start)
+
+	if err != nil {
+		log.Warn("outgoing request failed",
+			slog.String("method", r.Method),
+			slog.String("host", r.URL.Host),
+			slog.String("path", r.URL.Path),
+			slog.Duration("duration", duration),
+			slog.Any("error", err),
+		)
+		return nil, err
+	}
+
+	log.Info("outgoing request",
+		slog.String("method", r.Method),
+		slog.String("host", r.URL.Host),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("duration", duration),
+	)
+	if debug {
+		t.logResponseBody(log, r, resp)
+	}
+	return resp, nil
+}
+
+// logRequestBody logs a copy of the body obtained with GetBody, r is left untouched.
+func (t *loggingTransport) logRequestBody(log *slog.Logger, r *http.Request) {
+	if r.GetBody == nil {
+		return
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxObservedBytes+1))
+	if err != nil {
+		return
+	}
+	if payload, ok := t.sanitizedBody(r.Header.Get("Content-Type"), data); ok {
+		log.Debug("outgoing request body",
+			slog.String("method", r.Method),
+			slog.String("host", r.URL.Host),
+			slog.String("path", r.URL.Path),
+			slog.Any("payload", payload),
+		)
+	}
+}
+
+// logResponseBody reads up to maxObservedBytes of the response body and restores it.
+func (t *loggingTransport) logResponseBody(log *slog.Logger, r *http.Request, resp *http.Response) {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	var observed bytes.Buffer
+	_, err := io.Copy(&observed, io.LimitReader(resp.Body, maxObservedBytes+1))
+	resp.Body = readCloser{Reader: io.MultiReader(&observed, resp.Body), Closer: resp.Body}
+	if err != nil {
+		return
+	}
+
+	if payload, ok := t.sanitizedBody(resp.Header.Get("Content-Type"), observed.Bytes()); ok {
+		log.Debug("outgoing response body",
+			slog.String("method", r.Method),
+			slog.String("host", r.URL.Host),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", resp.StatusCode),
+			slog.Any("payload", payload),
+		)
+	}
+}
+
+// sanitizedBody decodes data when it is complete and of a known content type.
+func (t *loggingTransport) sanitizedBody(contentType string, data []byte) (any, bool) {
+	if len(data) == 0 || len(data) > maxObservedBytes {
+		return nil, false
+	}
+	decode, params, ok := findBodyDecoder(t.decoders, contentType)
+	if !ok {
+		return nil, false
+	}
+	payload, err := decode(data, params)
+	if err != nil {
+		return nil, false
+	}
+	Sanitize(payload)
+	return payload, true
+}
@@ -0,0 +1,288 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mama165/sdk-go/breaker"
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fastRetry() RetryConfig {
+	return RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+func TestClientRetriesIdempotentRequests(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient().WithRetry(fastRetry()).Build()
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	ass.Equal(http.StatusOK, resp.StatusCode)
+	ass.Equal("ok", string(body))
+	ass.Equal(int32(3), calls.Load())
+}
+
+func TestClientGivesUpAfterMaxAttempts(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().WithRetry(fastRetry()).Build().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ass.Equal(http.StatusBadGateway, resp.StatusCode)
+	ass.Equal(int32(3), calls.Load())
+}
+
+func TestClientDoesNotRetryPost(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().WithRetry(fastRetry()).Build().Post(server.URL, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ass.Equal(int32(1), calls.Load())
+}
+
+func TestClientRetriesPostWithIdempotencyKey(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"id":1}`))
+	req.Header.Set(DefaultIdempotencyHeader, "key-1")
+	resp, err := NewClient().WithRetry(fastRetry()).Build().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ass.Equal(http.StatusCreated, resp.StatusCode)
+	ass.Equal([]string{`{"id":1}`, `{"id":1}`}, bodies)
+}
+
+func TestClientStopsWhenRetryAfterIsTooLong(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient().WithRetry(fastRetry()).Build().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ass.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	ass.Equal(int32(1), calls.Load())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	ass := assert.New(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := parseRetryAfter("3", now)
+	ass.True(ok)
+	ass.Equal(3*time.Second, d)
+
+	d, ok = parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now)
+	ass.True(ok)
+	ass.Equal(10*time.Second, d)
+
+	_, ok = parseRetryAfter("soon", now)
+	ass.False(ok)
+	_, ok = parseRetryAfter("-1", now)
+	ass.False(ok)
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	ass := assert.New(t)
+
+	rt := newRetryTransport(http.DefaultTransport, RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}, nil)
+	for attempt := 1; attempt < 100; attempt++ {
+		d := rt.backoff(attempt)
+		ass.Greater(d, time.Duration(0))
+		ass.LessOrEqual(d, time.Second)
+	}
+	ass.LessOrEqual(rt.backoff(1), 100*time.Millisecond)
+}
+
+func TestClientAttemptTimeoutIsRetried(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	client := NewClient().WithAttemptTimeout(50 * time.Millisecond).WithRetry(fastRetry()).Build()
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	ass.Equal("ok", string(body))
+	ass.Equal(int32(2), calls.Load())
+}
+
+func TestClientLogsSanitizedBodies(t *testing.T) {
+	ass := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"access_token":"abc","user":"bob"}`)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelDebug)
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/login", strings.NewReader(`{"password":"secret","user":"bob"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := NewClient().WithLogging(logger).Build().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	ass.JSONEq(`{"access_token":"abc","user":"bob"}`, string(body))
+
+	out := buf.String()
+	ass.Contains(out, "outgoing request body")
+	ass.Contains(out, "outgoing response body")
+	ass.Contains(out, "/login")
+	ass.NotContains(out, "secret")
+	ass.NotContains(out, "abc")
+}
+
+func TestClientPropagatesRequestAndTraceIDs(t *testing.T) {
+	ass := assert.New(t)
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithTraceContext(ctx, TraceContext{TraceParent: traceParent, TraceState: "vendor=1"})
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := NewClient().Build().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ass.Equal("req-1", header.Get(DefaultRequestIDHeader))
+	// Same trace, new parent-id
+	ass.Equal("4bf92f3577b34da6a3ce929d0e0e4736", TraceContext{TraceParent: header.Get("traceparent")}.TraceID())
+	ass.NotEqual(traceParent, header.Get("traceparent"))
+	ass.Equal("vendor=1", header.Get("tracestate"))
+}
+
+func TestClientUseWrapsEveryAttempt(t *testing.T) {
+	ass := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var attempts atomic.Int32
+	counter := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			attempts.Add(1)
+			return next.RoundTrip(r)
+		})
+	}
+
+	resp, err := NewClient().WithRetry(fastRetry()).Use(counter).Build().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	ass.Equal(int32(3), attempts.Load())
+}
+
+func TestClientDoesNotRetryOpenCircuit(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var attempts atomic.Int32
+	counter := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			attempts.Add(1)
+			return next.RoundTrip(r)
+		})
+	}
+	set := breaker.NewSet(breaker.Config{Policy: breaker.ConsecutiveFailures(1)})
+	client := NewClient().WithRetry(fastRetry()).Use(counter, breaker.Transport(set, breaker.TransportConfig{})).Build()
+
+	_, err := client.Get(server.URL)
+	ass.ErrorIs(err, breaker.ErrOpen)
+	// The 503 opens the circuit, the rejected retry is not retried again
+	ass.Equal(int32(2), attempts.Load())
+	ass.Equal(int32(1), calls.Load())
+}
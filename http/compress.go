@@ -0,0 +1,312 @@
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content encodings.
+const (
+	EncodingZstd    = "zstd"
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// CompressConfig configures CompressMiddleware.
+type CompressConfig struct {
+	// MinSize is the body size under which responses are sent uncompressed (default: 1 KB).
+	// Streamed responses flushed before reaching it are compressed anyway
+	MinSize int
+	// Encodings lists the accepted encodings by server preference (default: zstd, gzip, deflate)
+	Encodings []string
+	// SkipContentTypes lists the media types (or "type/" prefixes) never compressed
+	// (default: DefaultSkipContentTypes)
+	SkipContentTypes []string
+}
+
+// DefaultSkipContentTypes lists media types that are already compressed.
+var DefaultSkipContentTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"font/woff2",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+	"application/octet-stream",
+	"text/event-stream",
+}
+
+// CompressMiddleware returns an HTTP middleware compressing responses.
+//
+// Behavior:
+//   - Negotiates the encoding from Accept-Encoding (q-values, "*" and "identity;q=0" included),
+//     ties being broken by CompressConfig.Encodings order.
+//   - Always adds "Vary: Accept-Encoding".
+//   - Buffers the first MinSize bytes to decide: small bodies, skipped content types,
+//     responses already encoded, partial (206) or bodiless responses are sent as is.
+//   - Sniffs the Content-Type on the uncompressed bytes when the handler did not set it.
+//   - Removes Content-Length when compressing.
+//   - Supports streaming: Flush forwards the compressed bytes written so far.
+//
+// Example usage:
+//
+//	http.Handle("/api", CompressMiddleware(CompressConfig{})(JSON(myHandler)))
+func CompressMiddleware(cfg CompressConfig) func(http.Handler) http.Handler {
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1 << 10
+	}
+	if len(cfg.Encodings) == 0 {
+		cfg.Encodings = []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+	}
+	if cfg.SkipContentTypes == nil {
+		cfg.SkipContentTypes = DefaultSkipContentTypes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, cfg: cfg, encoding: encoding, status: http.StatusOK}
+			// Not deferred: on panic nothing buffered must be sent, so that RecoveryMiddleware can answer
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// negotiateEncoding picks the preferred supported encoding from an Accept-Encoding header.
+func negotiateEncoding(accept string, supported []string) string {
+	if accept == "" {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		qualities[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := qualities[enc]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+var (
+	gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	zlibPool = sync.Pool{New: func() any { return zlib.NewWriter(io.Discard) }}
+	zstdPool = sync.Pool{New: func() any {
+		enc, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return enc
+	}}
+)
+
+// compressor is the common interface of the pooled encoders.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func acquireCompressor(encoding string, w io.Writer) compressor {
+	var c compressor
+	switch encoding {
+	case EncodingZstd:
+		c = zstdPool.Get().(*zstd.Encoder)
+	case EncodingGzip:
+		c = gzipPool.Get().(*gzip.Writer)
+	case EncodingDeflate:
+		c = zlibPool.Get().(*zlib.Writer)
+	default:
+		return nil
+	}
+	c.Reset(w)
+	return c
+}
+
+func releaseCompressor(encoding string, c compressor) {
+	c.Reset(io.Discard)
+	switch encoding {
+	case EncodingZstd:
+		zstdPool.Put(c)
+	case EncodingGzip:
+		gzipPool.Put(c)
+	case EncodingDeflate:
+		zlibPool.Put(c)
+	}
+}
+
+// compressWriter buffers the beginning of the body until it can decide whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      CompressConfig
+	encoding string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	compressor  compressor
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	// Informational responses are forwarded as is
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.status = code
+	cw.wroteHeader = true
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.cfg.MinSize {
+			return len(b), nil
+		}
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what was buffered, compressed if eligible whatever its size.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		_ = cw.decide(true)
+	}
+	if cw.compressor != nil {
+		_ = cw.compressor.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide writes the header and the buffered bytes, compressing them if large enough and eligible.
+func (cw *compressWriter) decide(largeEnough bool) error {
+	cw.decided = true
+	h := cw.Header()
+
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if largeEnough && cw.eligible() {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.compressor = acquireCompressor(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.compressor != nil {
+		_, err := cw.compressor.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+func (cw *compressWriter) eligible() bool {
+	switch cw.status {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return true
+	}
+	for _, skip := range cw.cfg.SkipContentTypes {
+		if mediaType == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(mediaType, skip)) {
+			return false
+		}
+	}
+	return true
+}
+
+// close sends a small body uncompressed and terminates the compressed stream.
+func (cw *compressWriter) close() {
+	if !cw.wroteHeader {
+		// Nothing written: let net/http send its implicit 200
+		return
+	}
+	if !cw.decided {
+		_ = cw.decide(false)
+	}
+	if cw.compressor != nil {
+		_ = cw.compressor.Close()
+		releaseCompressor(cw.encoding, cw.compressor)
+		cw.compressor = nil
+	}
+}
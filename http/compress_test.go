@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	ass := assert.New(t)
+	supported := []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+
+	ass.Equal(EncodingZstd, negotiateEncoding("gzip, deflate, br, zstd", supported))
+	ass.Equal(EncodingGzip, negotiateEncoding("gzip;q=1.0, zstd;q=0.5", supported))
+	ass.Equal(EncodingDeflate, negotiateEncoding("*;q=0.1, deflate", supported))
+	ass.Equal(EncodingGzip, negotiateEncoding("zstd;q=0, *", []string{EncodingZstd, EncodingGzip}))
+	ass.Equal("", negotiateEncoding("br", supported))
+	ass.Equal("", negotiateEncoding("identity", supported))
+	ass.Equal("", negotiateEncoding("", supported))
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	var r io.Reader
+	var err error
+	switch encoding {
+	case EncodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case EncodingDeflate:
+		r, err = zlib.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(bytes.NewReader(body))
+		r = d
+	}
+	assert.NoError(t, err)
+	plain, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(plain)
+}
+
+func TestCompressLargeJSON(t *testing.T) {
+	large := strings.Repeat("a", 4096)
+
+	for _, encoding := range []string{EncodingZstd, EncodingGzip, EncodingDeflate} {
+		t.Run(encoding, func(t *testing.T) {
+			ass := assert.New(t)
+
+			handler := CompressMiddleware(CompressConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+				return OK(large)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", encoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			ass.Equal(http.StatusOK, rec.Code)
+			ass.Equal(encoding, rec.Header().Get("Content-Encoding"))
+			ass.Equal("Accept-Encoding", rec.Header().Get("Vary"))
+			ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+			ass.Empty(rec.Header().Get("Content-Length"))
+			ass.Less(rec.Body.Len(), len(large))
+			ass.Equal(`"`+large+`"`+"\n", decompress(t, encoding, rec.Body.Bytes()))
+		})
+	}
+}
+
+func TestCompressSkipsSmallBody(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CompressMiddleware(CompressConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return Created("small")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusCreated, rec.Code)
+	ass.Empty(rec.Header().Get("Content-Encoding"))
+	ass.Equal("Accept-Encoding", rec.Header().Get("Vary"))
+	ass.Equal(`"small"`+"\n", rec.Body.String())
+}
+
+func TestCompressSkipsCompressedContentType(t *testing.T) {
+	ass := assert.New(t)
+
+	data := strings.Repeat("x", 4096)
+	handler := CompressMiddleware(CompressConfig{})(Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(strings.NewReader(data)).SetContentType("image/png")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Empty(rec.Header().Get("Content-Encoding"))
+	ass.Equal(data, rec.Body.String())
+}
+
+func TestCompressFlushStreamsSmallChunks(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CompressMiddleware(CompressConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("chunk-1"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("chunk-2"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.True(rec.Flushed)
+	ass.Equal(EncodingGzip, rec.Header().Get("Content-Encoding"))
+	ass.Equal("chunk-1chunk-2", decompress(t, EncodingGzip, rec.Body.Bytes()))
+}
+
+func TestCompressWithoutAcceptEncoding(t *testing.T) {
+	ass := assert.New(t)
+
+	large := strings.Repeat("a", 4096)
+	handler := CompressMiddleware(CompressConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(large)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ass.Empty(rec.Header().Get("Content-Encoding"))
+	ass.Equal("Accept-Encoding", rec.Header().Get("Vary"))
+	ass.Equal(`"`+large+`"`+"\n", rec.Body.String())
+}
+
+func TestCompressNoContent(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := CompressMiddleware(CompressConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return NoContent()
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusNoContent, rec.Code)
+	ass.Empty(rec.Header().Get("Content-Encoding"))
+	ass.Equal(0, rec.Body.Len())
+}
@@ -0,0 +1,272 @@
+package http
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy describes what a set of origins is allowed to do.
+type CORSPolicy struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"), subdomain wildcards
+	// ("https://*.example.com") or "*" for any origin
+	AllowedOrigins []string
+	// AllowedOriginPatterns matches origins with regular expressions, e.g. `^https://pr-\d+\.example\.com$`
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowedMethods (default: GET, HEAD, POST)
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers allowed in preflights, "*" allows any header
+	// (default: Accept, Authorization, Content-Type, X-Request-ID)
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers readable by the browser
+	ExposedHeaders []string
+	// AllowCredentials lets the browser send cookies and authorization headers,
+	// it cannot be combined with the "*" origin
+	AllowCredentials bool
+	// MaxAge is how long the browser can cache a preflight response
+	MaxAge time.Duration
+}
+
+// CORSConfig configures CORSMiddleware.
+type CORSConfig struct {
+	// Policies are tried in order, the first one allowing the origin applies
+	Policies []CORSPolicy
+	// Routes overrides Policies for the paths starting with a prefix, the longest prefix wins
+	Routes map[string][]CORSPolicy
+}
+
+// CORSMiddleware returns an HTTP middleware handling Cross-Origin Resource Sharing.
+//
+// Behavior:
+//   - Selects the policies of the longest matching route prefix (or the default ones),
+//     then the first policy allowing the Origin.
+//   - Answers preflight requests (OPTIONS with Access-Control-Request-Method) with 204
+//     without calling the next handler, so they are never seen by LogJSONBodyMiddleware.
+//     A rejected preflight gets no CORS header and the browser blocks the request.
+//   - Adds the CORS headers to actual requests from allowed origins.
+//   - Always adds "Vary: Origin" so that caches do not mix responses of different origins.
+//   - Never allows the "null" origin (sandboxed iframes, file:// pages, some redirects),
+//     any site can send it.
+//
+// ⚠️ A policy allowing any origin ("*") with AllowCredentials panics when the middleware is built:
+// any site could make authenticated requests with the cookies of its visitors.
+//
+// Register it around the ServeMux: preflights of method-specific patterns ("POST /items")
+// would otherwise be answered 405 by the mux.
+//
+// Example usage:
+//
+//	cors := CORSMiddleware(CORSConfig{
+//		Policies: []CORSPolicy{{
+//			AllowedOrigins:   []string{"https://*.example.com"},
+//			AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+//			AllowCredentials: true,
+//			MaxAge:           time.Hour,
+//		}},
+//		Routes: map[string][]CORSPolicy{"/public/": {{AllowedOrigins: []string{"*"}}}},
+//	})
+//	http.ListenAndServe(":8080", cors(mux))
+func CORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	defaults := compileCORSPolicies(cfg.Policies)
+	routes := make(map[string][]corsPolicy, len(cfg.Routes))
+	for prefix, policies := range cfg.Routes {
+		routes[prefix] = compileCORSPolicies(policies)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			policies := defaults
+			longest := -1
+			for prefix, p := range routes {
+				if len(prefix) > longest && strings.HasPrefix(r.URL.Path, prefix) {
+					policies, longest = p, len(prefix)
+				}
+			}
+
+			var policy *corsPolicy
+			if origin != "" {
+				for i := range policies {
+					if policies[i].allowsOrigin(origin) {
+						policy = &policies[i]
+						break
+					}
+				}
+			}
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				if policy != nil {
+					policy.preflight(w, r, origin)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if policy != nil {
+				policy.actual(w, origin)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// corsPolicy is a CORSPolicy with defaults applied and lookups prepared.
+type corsPolicy struct {
+	CORSPolicy
+	anyOrigin   bool
+	exact       map[string]bool
+	wildcards   [][2]string // scheme://*.suffix split around "*"
+	methods     map[string]bool
+	anyHeader   bool
+	headers     map[string]bool
+	methodsList string
+	headersList string
+	exposedList string
+}
+
+func compileCORSPolicies(policies []CORSPolicy) []corsPolicy {
+	compiled := make([]corsPolicy, 0, len(policies))
+	for _, p := range policies {
+		compiled = append(compiled, compileCORSPolicy(p))
+	}
+	return compiled
+}
+
+func compileCORSPolicy(p CORSPolicy) corsPolicy {
+	if p.AllowCredentials && slices.Contains(p.AllowedOrigins, "*") {
+		panic(`http: a CORS policy cannot allow credentials from any origin ("*")`)
+	}
+	if len(p.AllowedMethods) == 0 {
+		p.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	if len(p.AllowedHeaders) == 0 {
+		p.AllowedHeaders = []string{"Accept", "Authorization", "Content-Type", DefaultRequestIDHeader}
+	}
+
+	c := corsPolicy{
+		CORSPolicy: p,
+		exact:      make(map[string]bool),
+		methods:    make(map[string]bool),
+		headers:    make(map[string]bool),
+	}
+
+	for _, o := range p.AllowedOrigins {
+		o = strings.ToLower(o)
+		switch {
+		case o == "*":
+			c.anyOrigin = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		default:
+			c.exact[o] = true
+		}
+	}
+
+	for _, m := range p.AllowedMethods {
+		c.methods[strings.ToUpper(m)] = true
+	}
+	for _, h := range p.AllowedHeaders {
+		if h == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers[http.CanonicalHeaderKey(h)] = true
+	}
+
+	c.methodsList = strings.Join(p.AllowedMethods, ", ")
+	c.headersList = strings.Join(p.AllowedHeaders, ", ")
+	c.exposedList = strings.Join(p.ExposedHeaders, ", ")
+	return c
+}
+
+func (c *corsPolicy) allowsOrigin(origin string) bool {
+	if strings.EqualFold(origin, "null") {
+		return false
+	}
+	if c.anyOrigin {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if c.exact[lower] {
+		return true
+	}
+	for _, w := range c.wildcards {
+		// The wildcard must match at least one character
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(c.AllowedOriginPatterns, func(re *regexp.Regexp) bool {
+		return re.MatchString(origin)
+	})
+}
+
+func (c *corsPolicy) allowOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *corsPolicy) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !c.methods[method] {
+		return
+	}
+
+	requested := parseHeaderList(r.Header.Values("Access-Control-Request-Headers"))
+	if !c.anyHeader {
+		for _, h := range requested {
+			if !c.headers[http.CanonicalHeaderKey(h)] {
+				return
+			}
+		}
+	}
+
+	c.allowOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", c.methodsList)
+	if c.anyHeader {
+		// "*" is not honored by browsers for credentialed requests, the requested headers are echoed
+		if len(requested) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+	} else {
+		w.Header().Set("Access-Control-Allow-Headers", c.headersList)
+	}
+	if c.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+	}
+}
+
+func (c *corsPolicy) actual(w http.ResponseWriter, origin string) {
+	c.allowOrigin(w, origin)
+	if c.exposedList != "" {
+		w.Header().Set("Access-Control-Expose-Headers", c.exposedList)
+	}
+}
+
+func parseHeaderList(values []string) []string {
+	var list []string
+	for _, v := range values {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				list = append(list, h)
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func corsHandler(cfg CORSConfig, called *bool) http.Handler {
+	return CORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	ass := assert.New(t)
+
+	var called bool
+	handler := corsHandler(CORSConfig{Policies: []CORSPolicy{{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}}}, &called)
+
+	req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "content-type, x-request-id")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.False(called)
+	ass.Equal(http.StatusNoContent, rec.Code)
+	ass.Equal("https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	ass.Equal("true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	ass.Equal("GET, PUT", rec.Header().Get("Access-Control-Allow-Methods"))
+	ass.Equal("3600", rec.Header().Get("Access-Control-Max-Age"))
+	ass.Equal([]string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, rec.Header().Values("Vary"))
+}
+
+func TestCORSPreflightRejected(t *testing.T) {
+	ass := assert.New(t)
+
+	var called bool
+	handler := corsHandler(CORSConfig{Policies: []CORSPolicy{{
+		AllowedOrigins: []string{"https://app.example.com"},
+	}}}, &called)
+
+	for _, tc := range []struct{ origin, method, headers string }{
+		{"https://evil.com", http.MethodGet, ""},
+		{"https://app.example.com", http.MethodDelete, ""},
+		{"https://app.example.com", http.MethodPost, "X-Custom"},
+	} {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", tc.method)
+		if tc.headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", tc.headers)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		ass.Equal(http.StatusNoContent, rec.Code)
+		ass.Empty(rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+	ass.False(called)
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	ass := assert.New(t)
+
+	var called bool
+	handler := corsHandler(CORSConfig{Policies: []CORSPolicy{{
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://pr-\d+\.example\.com$`)},
+		ExposedHeaders:        []string{DefaultRequestIDHeader},
+	}}}, &called)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://pr-42.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.True(called)
+	ass.Equal("https://pr-42.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	ass.Equal(DefaultRequestIDHeader, rec.Header().Get("Access-Control-Expose-Headers"))
+	ass.Equal("Origin", rec.Header().Get("Vary"))
+
+	// Unknown origins still reach the handler, without CORS headers
+	called = false
+	req.Header.Set("Origin", "https://pr-x.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.True(called)
+	ass.Empty(rec.Header().Get("Access-Control-Allow-Origin"))
+	ass.Equal("Origin", rec.Header().Get("Vary"))
+}
+
+func TestCORSPerOriginAndRoutePolicies(t *testing.T) {
+	ass := assert.New(t)
+
+	var called bool
+	handler := corsHandler(CORSConfig{
+		Policies: []CORSPolicy{
+			{AllowedOrigins: []string{"https://admin.example.com"}, AllowCredentials: true},
+			{AllowedOrigins: []string{"*"}},
+		},
+		Routes: map[string][]CORSPolicy{
+			"/private/": {{AllowedOrigins: []string{"https://admin.example.com"}}},
+		},
+	}, &called)
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal("https://admin.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	ass.Equal("true", rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	req.Header.Set("Origin", "https://other.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Equal("*", rec.Header().Get("Access-Control-Allow-Origin"))
+	ass.Empty(rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	req = httptest.NewRequest(http.MethodGet, "/private/items", nil)
+	req.Header.Set("Origin", "https://other.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	ass.Empty(rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSAnyHeaderEchoesRequestedHeaders(t *testing.T) {
+	ass := assert.New(t)
+
+	var called bool
+	handler := corsHandler(CORSConfig{Policies: []CORSPolicy{{
+		AllowedOrigins: []string{"*"},
+		AllowedHeaders: []string{"*"},
+	}}}, &called)
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom, X-Other")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal("*", rec.Header().Get("Access-Control-Allow-Origin"))
+	ass.Equal("X-Custom, X-Other", rec.Header().Get("Access-Control-Allow-Headers"))
+}
+
+func TestCORSRejectsCredentialsFromAnyOrigin(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Panics(func() {
+		CORSMiddleware(CORSConfig{Policies: []CORSPolicy{{AllowedOrigins: []string{"*"}, AllowCredentials: true}}})
+	})
+	ass.Panics(func() {
+		CORSMiddleware(CORSConfig{Routes: map[string][]CORSPolicy{
+			"/api/": {{AllowedOrigins: []string{"https://app.example.com", "*"}, AllowCredentials: true}},
+		}})
+	})
+}
+
+func TestCORSNeverAllowsNullOrigin(t *testing.T) {
+	ass := assert.New(t)
+
+	for _, origins := range [][]string{{"*"}, {"null"}} {
+		var called bool
+		handler := corsHandler(CORSConfig{Policies: []CORSPolicy{{AllowedOrigins: origins}}}, &called)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "null")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		ass.True(called)
+		ass.Empty(rec.Header().Get("Access-Control-Allow-Origin"), origins)
+	}
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DecompressConfig configures DecompressMiddleware.
+type DecompressConfig struct {
+	// MaxBytes caps the decompressed body size to defeat zip bombs (default: 10 MB)
+	MaxBytes int64
+}
+
+// DecompressMiddleware returns an HTTP middleware decoding compressed request bodies.
+//
+// Behavior:
+//   - Decodes Content-Encoding gzip (x-gzip), deflate and zstd, including stacked
+//     encodings ("gzip, zstd" is decoded from right to left). identity is ignored.
+//   - Rejects other encodings with 415 (UnsupportedMediaType payload) and
+//     malformed gzip/deflate headers with 400.
+//   - Decodes lazily: reading past MaxBytes decompressed bytes fails with *http.MaxBytesError,
+//     which BodyLimitMiddleware turns into a 413.
+//   - Removes Content-Encoding and Content-Length so that the next handlers see a plain body.
+//
+// Register it before BodyLimitMiddleware and LogJSONBodyMiddleware so they see plain JSON.
+//
+// Example usage:
+//
+//	chain := NewChain(DecompressMiddleware(DecompressConfig{}), LogJSONBodyMiddleware(logger))
+//	http.Handle("/upload", chain.Then(myHandler))
+func DecompressMiddleware(cfg DecompressConfig) func(http.Handler) http.Handler {
+	maxBytes := cfg.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 10 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encodings := parseContentEncoding(r.Header.Values("Content-Encoding"))
+			if len(encodings) == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var body io.ReadCloser = r.Body
+			for i := len(encodings) - 1; i >= 0; i-- {
+				decoded, err := newDecompressor(encodings[i], body, maxBytes)
+				if err != nil {
+					_ = body.Close()
+					if _, ok := err.(unsupportedEncodingError); ok {
+						respondWithJSON(w, UnsupportedMediaType(err.Error()))
+						return
+					}
+					respondWithJSON(w, BadRequest(err.Error()))
+					return
+				}
+				body = decoded
+			}
+
+			r.Body = http.MaxBytesReader(w, body, maxBytes)
+			r.ContentLength = -1
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type unsupportedEncodingError string
+
+func (e unsupportedEncodingError) Error() string {
+	return fmt.Sprintf("unsupported content encoding %q", string(e))
+}
+
+// parseContentEncoding lists the encodings applied to the body, in application order.
+func parseContentEncoding(values []string) []string {
+	var encodings []string
+	for _, v := range values {
+		for _, enc := range strings.Split(v, ",") {
+			enc = strings.ToLower(strings.TrimSpace(enc))
+			if enc != "" && enc != "identity" {
+				encodings = append(encodings, enc)
+			}
+		}
+	}
+	return encodings
+}
+
+// newDecompressor wraps body with the decoder of encoding. Closing the result closes body.
+func newDecompressor(encoding string, body io.ReadCloser, maxBytes int64) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		return readCloser{Reader: zr, Closer: closers{zr, body}}, nil
+
+	case EncodingDeflate:
+		zr, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate body: %w", err)
+		}
+		return readCloser{Reader: zr, Closer: closers{zr, body}}, nil
+
+	case EncodingZstd:
+		zr, err := zstd.NewReader(body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(maxBytes)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		return readCloser{Reader: zr, Closer: closers{zr.IOReadCloser(), body}}, nil
+
+	default:
+		return nil, unsupportedEncodingError(encoding)
+	}
+}
+
+// closers closes every element, returning the first error.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var first error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingDeflate:
+		w = zlib.NewWriter(&buf)
+	case EncodingZstd:
+		enc, err := zstd.NewWriter(&buf)
+		assert.NoError(t, err)
+		w = enc
+	}
+	_, err := w.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecompressBody(t *testing.T) {
+	payload := `{"name":"john"}`
+
+	for _, encoding := range []string{EncodingGzip, EncodingDeflate, EncodingZstd} {
+		t.Run(encoding, func(t *testing.T) {
+			ass := assert.New(t)
+
+			handler := DecompressMiddleware(DecompressConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				read, err := io.ReadAll(r.Body)
+				ass.NoError(err)
+				ass.Equal(payload, string(read))
+				ass.Empty(r.Header.Get("Content-Encoding"))
+				ass.Equal(int64(-1), r.ContentLength)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compress(t, encoding, []byte(payload))))
+			req.Header.Set("Content-Encoding", encoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			ass.Equal(http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestDecompressStackedEncodings(t *testing.T) {
+	ass := assert.New(t)
+
+	payload := "hello"
+	body := compress(t, EncodingZstd, compress(t, EncodingGzip, []byte(payload)))
+
+	handler := DecompressMiddleware(DecompressConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, err := io.ReadAll(r.Body)
+		ass.NoError(err)
+		ass.Equal(payload, string(read))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Encoding", "gzip, zstd")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestDecompressUnsupportedEncoding(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := DecompressMiddleware(DecompressConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("data"))
+	req.Header.Set("Content-Encoding", "br")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusUnsupportedMediaType, rec.Code)
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("unsupported media type", body["message"])
+}
+
+func TestDecompressMalformedBody(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := DecompressMiddleware(DecompressConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func TestDecompressZipBombIsRejected(t *testing.T) {
+	ass := assert.New(t)
+
+	bomb := compress(t, EncodingGzip, bytes.Repeat([]byte("0"), 1<<20))
+	chain := NewChain(
+		DecompressMiddleware(DecompressConfig{MaxBytes: 1 << 10}),
+		BodyLimitMiddleware(BodyLimits{}),
+	)
+	handler := chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+	ass.Contains(rec.Body.String(), "body exceeds 1024 bytes")
+}
+
+func TestDecompressedBodyIsLogged(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelDebug)
+
+	chain := NewChain(DecompressMiddleware(DecompressConfig{}), LogJSONBodyMiddleware(logger))
+	handler := chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(compress(t, EncodingGzip, []byte(`{"password":"secret","name":"john"}`))))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var logEntry map[string]any
+	ass.NoError(json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &logEntry))
+	ass.Equal(map[string]any{"password": "*****", "name": "john"}, logEntry["body"])
+}
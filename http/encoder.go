@@ -0,0 +1,426 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrUnsupportedPayload is returned by an Encoder that cannot represent a payload,
+// the negotiation then tries the next acceptable encoder.
+var ErrUnsupportedPayload = errors.New("payload not supported by this encoder")
+
+// Encoder writes v in the media type it is registered with.
+type Encoder func(w io.Writer, v any) error
+
+// EncoderRegistry holds the representations a Response payload can be written in,
+// safe for concurrent use.
+//
+// Behavior:
+//   - The representation is picked from the Accept header of the request, by quality then
+//     specificity. Wildcards ("*/*", "application/*") pick the first registered encoder
+//     able to encode the payload, JSON by default. A missing Accept header means JSON.
+//   - Response.SetContentType forces a registered representation whatever Accept.
+//   - Nothing acceptable answers 406 (NotAcceptable payload, in JSON).
+//     Error responses are written in JSON instead.
+//   - Built-in encoders, in preference order:
+//   - application/json (protojson for proto.Message payloads);
+//   - application/xml and text/xml, maps are written as a <response> element;
+//   - application/cbor and application/msgpack, from the JSON representation of the payload;
+//   - application/x-protobuf and application/protobuf for proto.Message payloads only;
+//   - text/csv for slices of structs only, with a header row named after the json tags.
+//   - iter.Seq[T] and <-chan T payloads are streamed as a JSON array or NDJSON instead,
+//     see respondStream.
+//
+// Example usage:
+//
+//	DefaultEncoders.Register("application/yaml", func(w io.Writer, v any) error {
+//		return yaml.NewEncoder(w).Encode(v)
+//	})
+type EncoderRegistry struct {
+	mu       sync.RWMutex
+	encoders []registeredEncoder
+}
+
+type registeredEncoder struct {
+	mediaType   string
+	contentType string
+	encode      Encoder
+}
+
+// DefaultEncoders is used by JSON, Stream, Typed and ErrorRegistry.JSON.
+var DefaultEncoders = NewEncoderRegistry()
+
+// NewEncoderRegistry creates a registry with the built-in encoders.
+func NewEncoderRegistry() *EncoderRegistry {
+	reg := &EncoderRegistry{}
+	reg.Register("application/json; charset=utf-8", EncodeJSON)
+	reg.Register("application/xml; charset=utf-8", EncodeXML)
+	reg.Register("text/xml; charset=utf-8", EncodeXML)
+	reg.Register("application/cbor", EncodeCBOR)
+	reg.Register("application/msgpack", EncodeMessagePack)
+	reg.Register("application/x-msgpack", EncodeMessagePack)
+	reg.Register("application/x-protobuf", EncodeProtobuf)
+	reg.Register("application/protobuf", EncodeProtobuf)
+	reg.Register("text/csv; charset=utf-8", EncodeCSV)
+	return reg
+}
+
+// Register adds an encoder for contentType, e.g. "text/csv; charset=utf-8",
+// or replaces the encoder of the same media type.
+// ⚠️ An invalid content type panics, it is a programming error.
+func (reg *EncoderRegistry) Register(contentType string, enc Encoder) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		panic(fmt.Sprintf("http: invalid content type %q", contentType))
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	entry := registeredEncoder{mediaType: mediaType, contentType: contentType, encode: enc}
+	if i := slices.IndexFunc(reg.encoders, func(e registeredEncoder) bool { return e.mediaType == mediaType }); i >= 0 {
+		reg.encoders[i] = entry
+		return
+	}
+	reg.encoders = append(reg.encoders, entry)
+}
+
+// MediaTypes lists the registered media types in preference order.
+func (reg *EncoderRegistry) MediaTypes() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	types := make([]string, len(reg.encoders))
+	for i, e := range reg.encoders {
+		types[i] = e.mediaType
+	}
+	return types
+}
+
+// errNotAcceptable means that no encoder matches the Accept header.
+var errNotAcceptable = errors.New("not acceptable")
+
+// encode writes the payload in the representation negotiated with accept, or forced by contentType.
+func (reg *EncoderRegistry) encode(accept, contentType string, payload any) ([]byte, string, error) {
+	reg.mu.RLock()
+	encoders := slices.Clone(reg.encoders)
+	reg.mu.RUnlock()
+
+	var buf bytes.Buffer
+	try := func(e registeredEncoder) (bool, error) {
+		buf.Reset()
+		err := e.encode(&buf, payload)
+		if errors.Is(err, ErrUnsupportedPayload) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	if contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			if i := slices.IndexFunc(encoders, func(e registeredEncoder) bool { return e.mediaType == mediaType }); i >= 0 {
+				if ok, err := try(encoders[i]); ok || err != nil {
+					return buf.Bytes(), encoders[i].contentType, err
+				}
+			}
+		}
+	}
+
+	ranges, excluded := parseAccept(accept)
+	for _, mediaRange := range ranges {
+		for _, e := range encoders {
+			if excluded[e.mediaType] || !mediaRange.matches(e.mediaType) {
+				continue
+			}
+			if ok, err := try(e); ok || err != nil {
+				return buf.Bytes(), e.contentType, err
+			}
+		}
+	}
+	return nil, "", errNotAcceptable
+}
+
+// mediaRange is an element of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	quality      float64
+}
+
+func (m mediaRange) matches(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (m.typ == "*" || m.typ == typ) && (m.subtype == "*" || m.subtype == subtype)
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// parseAccept returns the acceptable media ranges by preference, and the media types refused with q=0.
+func parseAccept(accept string) ([]mediaRange, map[string]bool) {
+	if strings.TrimSpace(accept) == "" {
+		return []mediaRange{{typ: "application", subtype: "json", quality: 1}}, nil
+	}
+
+	var ranges []mediaRange
+	excluded := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			excluded[mediaType] = true
+			continue
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].quality != ranges[j].quality {
+			return ranges[i].quality > ranges[j].quality
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges, excluded
+}
+
+// EncodeJSON writes v as JSON, with protojson for proto.Message payloads.
+func EncodeJSON(w io.Writer, v any) error {
+	if msg, ok := v.(proto.Message); ok {
+		data, err := protojson.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	return json.NewEncoder(w).Encode(v)
+}
+
+// EncodeProtobuf writes proto.Message payloads in the protobuf wire format.
+func EncodeProtobuf(w io.Writer, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return ErrUnsupportedPayload
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// EncodeXML writes v with encoding/xml, maps (such as error payloads) become a <response> element
+// with one child per key, in key order.
+func EncodeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		if err := encodeXMLMap(enc, "response", rv); err != nil {
+			return err
+		}
+		return enc.Flush()
+	}
+	if err := enc.Encode(v); err != nil {
+		var unsupported *xml.UnsupportedTypeError
+		if errors.As(err, &unsupported) {
+			return ErrUnsupportedPayload
+		}
+		return err
+	}
+	return enc.Flush()
+}
+
+func encodeXMLMap(enc *xml.Encoder, name string, m reflect.Value) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		value := m.MapIndex(key)
+		for value.Kind() == reflect.Interface && !value.IsNil() {
+			value = value.Elem()
+		}
+		if value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String {
+			if err := encodeXMLMap(enc, key.String(), value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := enc.EncodeElement(value.Interface(), xml.StartElement{Name: xml.Name{Local: key.String()}}); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// EncodeCSV writes a slice of structs (or pointers to structs) with a header row.
+// Columns are the exported fields, named after their json tag. Nested values are written as JSON.
+func EncodeCSV(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return ErrUnsupportedPayload
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct || elem == timeType {
+		return ErrUnsupportedPayload
+	}
+
+	var columns []int
+	var header []string
+	for i := range elem.NumField() {
+		f := elem.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		columns = append(columns, i)
+		header = append(header, name)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for i := range rv.Len() {
+		item := rv.Index(i)
+		if item.Kind() == reflect.Pointer {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		for j, column := range columns {
+			cell, err := csvCell(item.Field(column))
+			if err != nil {
+				return err
+			}
+			record[j] = cell
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvCell(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	default:
+		data, err := json.Marshal(v.Interface())
+		return string(data), err
+	}
+}
+
+// respond writes resp with writeResponse, then runs its OnClose functions.
+func respond(w http.ResponseWriter, r *http.Request, resp *Response) {
+	defer resp.release()
+	writeResponse(w, r, resp)
+}
+
+// writeResponse writes resp in the representation negotiated with the request, see EncoderRegistry.
+func writeResponse(w http.ResponseWriter, r *http.Request, resp *Response) {
+	resp = withRequestInstance(resp, r)
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified ||
+		(resp.Payload == nil && resp.StatusCode >= 300 && resp.StatusCode < 400) || problemOf(resp) != nil {
+		respondWithJSON(w, resp)
+		return
+	}
+	if items, ok := streamedItems(r.Context(), resp.Payload); ok {
+		respondStream(w, r, resp, items)
+		return
+	}
+
+	body, contentType, err := DefaultEncoders.encode(r.Header.Get("Accept"), resp.contentType, resp.Payload)
+	if errors.Is(err, errNotAcceptable) {
+		// Another Accept header would be answered otherwise
+		w.Header().Add("Vary", "Accept")
+		if resp.StatusCode >= http.StatusBadRequest {
+			// The original error matters more than its representation
+			respondWithJSON(w, resp)
+			return
+		}
+		respondWithJSON(w, NotAcceptable("supported media types: "+strings.Join(DefaultEncoders.MediaTypes(), ", ")))
+		return
+	}
+	if err != nil {
+		http.Error(w, "encoding error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	for k, v := range resp.header {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(body)
+}
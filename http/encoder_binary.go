@@ -0,0 +1,216 @@
+package http
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// EncodeCBOR writes v in CBOR (RFC 8949), from its JSON representation so that json tags apply.
+// Map keys are sorted, integers use the shortest encoding and other numbers are float64.
+func EncodeCBOR(w io.Writer, v any) error {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeCBOR(&buf, tree); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// EncodeMessagePack writes v in MessagePack, from its JSON representation so that json tags apply.
+func EncodeMessagePack(w io.Writer, v any) error {
+	tree, err := jsonTree(v)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeMessagePack(&buf, tree); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// jsonTree converts v to the generic values of encoding/json, keeping numbers as json.Number.
+func jsonTree(v any) (any, error) {
+	var buf bytes.Buffer
+	if err := EncodeJSON(&buf, v); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var tree any
+	err := dec.Decode(&tree)
+	return tree, err
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+)
+
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major<<5 | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(major<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func writeCBOR(buf *bytes.Buffer, v any) error {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if t {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			if n >= 0 {
+				writeCBORHead(buf, cborUint, uint64(n))
+			} else {
+				writeCBORHead(buf, cborNegInt, uint64(-1-n))
+			}
+			return nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xfb)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(t)))
+		buf.WriteString(t)
+	case []any:
+		writeCBORHead(buf, cborArray, uint64(len(t)))
+		for _, item := range t {
+			if err := writeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		writeCBORHead(buf, cborMap, uint64(len(t)))
+		for _, k := range sortedKeys(t) {
+			writeCBORHead(buf, cborText, uint64(len(k)))
+			buf.WriteString(k)
+			if err := writeCBOR(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: unexpected %T", v)
+	}
+	return nil
+}
+
+// writeMessagePackSize writes the header of a str, array or map, picking the fix format when possible.
+func writeMessagePackSize(buf *bytes.Buffer, n int, fix byte, fixMax int, size16, size32 byte) {
+	switch {
+	case n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(size16)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buf.WriteByte(size32)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func writeMessagePackString(buf *bytes.Buffer, s string) {
+	if len(s) > 31 && len(s) <= math.MaxUint8 {
+		buf.WriteByte(0xd9) // str 8
+		buf.WriteByte(byte(len(s)))
+	} else {
+		writeMessagePackSize(buf, len(s), 0xa0, 31, 0xda, 0xdb)
+	}
+	buf.WriteString(s)
+}
+
+func writeMessagePack(buf *bytes.Buffer, v any) error {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if t {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			switch {
+			case n >= 0 && n <= math.MaxInt8:
+				buf.WriteByte(byte(n)) // positive fixint
+			case n < 0 && n >= -32:
+				buf.WriteByte(byte(int8(n))) // negative fixint
+			default:
+				buf.WriteByte(0xd3) // int 64
+				buf.Write(binary.BigEndian.AppendUint64(nil, uint64(n)))
+			}
+			return nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte(0xcb) // float 64
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+	case string:
+		writeMessagePackString(buf, t)
+	case []any:
+		writeMessagePackSize(buf, len(t), 0x90, 15, 0xdc, 0xdd)
+		for _, item := range t {
+			if err := writeMessagePack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		writeMessagePackSize(buf, len(t), 0x80, 15, 0xde, 0xdf)
+		for _, k := range sortedKeys(t) {
+			writeMessagePackString(buf, k)
+			if err := writeMessagePack(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: unexpected %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"iter"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// NDJSONContentType is the media type of newline delimited JSON, one value per line.
+const NDJSONContentType = "application/x-ndjson"
+
+// Flushing of streamed collections, whichever comes first.
+const (
+	streamFlushItems    = 100
+	streamFlushInterval = time.Second
+)
+
+// streamMediaTypes lists the representations of streamed collections in preference order.
+var streamMediaTypes = []string{"application/json", NDJSONContentType}
+
+// streamedItems returns the items of an iter.Seq[T] or receive channel payload,
+// stopping when ctx is done. ok is false for any other payload.
+func streamedItems(ctx context.Context, payload any) (items iter.Seq[any], ok bool) {
+	if payload == nil {
+		return nil, false
+	}
+	if seq, ok := payload.(iter.Seq[any]); ok {
+		return cancelableSeq(ctx, seq), true
+	}
+
+	rv := reflect.ValueOf(payload)
+	switch t := rv.Type(); {
+	case t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0:
+		return channelSeq(ctx, rv), true
+	case isSeq(t):
+		if rv.IsNil() {
+			return nil, false
+		}
+		return cancelableSeq(ctx, reflectSeq(rv)), true
+	default:
+		return nil, false
+	}
+}
+
+// isSeq tells whether t has the shape of iter.Seq[T]: func(yield func(T) bool).
+func isSeq(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	yield := t.In(0)
+	return yield.Kind() == reflect.Func && yield.NumIn() == 1 && yield.NumOut() == 1 &&
+		yield.Out(0).Kind() == reflect.Bool
+}
+
+func reflectSeq(fn reflect.Value) iter.Seq[any] {
+	yieldType := fn.Type().In(0)
+	return func(yield func(any) bool) {
+		fn.Call([]reflect.Value{reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+			return []reflect.Value{reflect.ValueOf(yield(args[0].Interface())).Convert(yieldType.Out(0))}
+		})})
+	}
+}
+
+func cancelableSeq(ctx context.Context, seq iter.Seq[any]) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for item := range seq {
+			if ctx.Err() != nil || !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+func channelSeq(ctx context.Context, ch reflect.Value) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: ch},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 1 || !ok || !yield(item.Interface()) {
+				return
+			}
+		}
+	}
+}
+
+// streamMediaType negotiates the representation of a streamed collection, or returns "".
+func streamMediaType(accept, contentType string) string {
+	if contentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			for _, m := range streamMediaTypes {
+				if m == mediaType {
+					return m
+				}
+			}
+		}
+	}
+
+	ranges, excluded := parseAccept(accept)
+	for _, mediaRange := range ranges {
+		for _, m := range streamMediaTypes {
+			if !excluded[m] && mediaRange.matches(m) {
+				return m
+			}
+		}
+	}
+	return ""
+}
+
+// respondStream writes a collection item by item, without holding it in memory.
+//
+// Behavior:
+//   - Payloads of type iter.Seq[T] or <-chan T are streamed, as a JSON array by default or as
+//     NDJSON (one item per line) when negotiated with Accept or forced with SetContentType.
+//   - Items are flushed every 100 items or every second, and before waiting on an empty channel.
+//   - The status is only written once the first item is encoded, so that an encoding error
+//     at that point still answers 500 (InternalError payload).
+//   - Streaming stops when the request context is done.
+//
+// ⚠️ An encoding error after the first item aborts the response with http.ErrAbortHandler:
+// the status is already sent, so the client sees a truncated body rather than a valid one.
+// RecoveryMiddleware lets it through.
+func respondStream(w http.ResponseWriter, r *http.Request, resp *Response, items iter.Seq[any]) {
+	mediaType := streamMediaType(r.Header.Get("Accept"), resp.contentType)
+	if mediaType == "" {
+		w.Header().Add("Vary", "Accept")
+		respondWithJSON(w, NotAcceptable("supported media types: application/json, "+NDJSONContentType))
+		return
+	}
+	ndjson := mediaType == NDJSONContentType
+
+	var buf bytes.Buffer
+	started, count, lastFlush := false, 0, time.Now()
+	start := func() {
+		started = true
+		if ndjson {
+			w.Header().Set("Content-Type", NDJSONContentType)
+		} else {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		w.Header().Add("Vary", "Accept")
+		for k, v := range resp.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.StatusCode)
+	}
+	flush := func() {
+		_, _ = w.Write(buf.Bytes())
+		buf.Reset()
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		lastFlush = time.Now()
+	}
+
+	var encodeErr error
+	for item := range items {
+		mark := buf.Len()
+		switch {
+		case ndjson:
+		case count == 0:
+			buf.WriteByte('[')
+		default:
+			buf.WriteByte(',')
+		}
+		if encodeErr = EncodeJSON(&buf, item); encodeErr != nil {
+			buf.Truncate(mark)
+			break
+		}
+		if !ndjson {
+			// Array items stay on a single line
+			buf.Truncate(buf.Len() - 1)
+		}
+		count++
+
+		if !started {
+			start()
+		}
+		if count%streamFlushItems == 0 || time.Since(lastFlush) >= streamFlushInterval || channelEmpty(resp.Payload) {
+			flush()
+		}
+	}
+
+	if encodeErr != nil {
+		if !started {
+			respondWithError(w, http.StatusInternalServerError, "encoding error", encodeErr.Error())
+			return
+		}
+		flush()
+		panic(http.ErrAbortHandler)
+	}
+
+	if !started {
+		start()
+	}
+	if !ndjson {
+		if count == 0 {
+			buf.WriteByte('[')
+		}
+		buf.WriteString("]\n")
+	}
+	flush()
+}
+
+// channelEmpty tells whether the next receive on a channel payload would block.
+func channelEmpty(payload any) bool {
+	rv := reflect.ValueOf(payload)
+	return rv.Kind() == reflect.Chan && rv.Len() == 0
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"iter"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func streamTo(t *testing.T, req *http.Request, resp *Response) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	JSON(func(w http.ResponseWriter, r *http.Request) *Response { return resp })(rec, req)
+	return rec
+}
+
+func TestStreamSeqAsJSONArray(t *testing.T) {
+	ass := assert.New(t)
+	users := []streamedUser{{1, "ann"}, {2, "bob"}}
+
+	rec := streamTo(t, httptest.NewRequest(http.MethodGet, "/users", nil), OK(slices.Values(users)))
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.Equal(`[{"id":1,"name":"ann"},{"id":2,"name":"bob"}]`+"\n", rec.Body.String())
+	ass.True(rec.Flushed)
+}
+
+func TestStreamEmptySeq(t *testing.T) {
+	rec := streamTo(t, httptest.NewRequest(http.MethodGet, "/users", nil), OK(slices.Values([]streamedUser{})))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]\n", rec.Body.String())
+}
+
+func TestStreamChannelAsNDJSON(t *testing.T) {
+	ass := assert.New(t)
+	ch := make(chan streamedUser, 3)
+	ch <- streamedUser{1, "ann"}
+	ch <- streamedUser{2, "bob"}
+	close(ch)
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Accept", NDJSONContentType)
+	rec := streamTo(t, req, OK((<-chan streamedUser)(ch)))
+
+	ass.Equal(NDJSONContentType, rec.Header().Get("Content-Type"))
+	ass.Equal("{\"id\":1,\"name\":\"ann\"}\n{\"id\":2,\"name\":\"bob\"}\n", rec.Body.String())
+}
+
+func TestStreamForcedNDJSON(t *testing.T) {
+	rec := streamTo(t, httptest.NewRequest(http.MethodGet, "/n", nil), OK(slices.Values([]int{1, 2})).SetContentType(NDJSONContentType))
+
+	assert.Equal(t, "1\n2\n", rec.Body.String())
+}
+
+func TestStreamNotAcceptable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/n", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec := streamTo(t, req, OK(slices.Values([]int{1})))
+
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+}
+
+func TestStreamEncodingErrorBeforeFirstItem(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := streamTo(t, httptest.NewRequest(http.MethodGet, "/n", nil), OK(slices.Values([]float64{math.NaN(), 1})))
+
+	ass.Equal(http.StatusInternalServerError, rec.Code)
+	ass.Contains(rec.Body.String(), `"encoding error"`)
+}
+
+func TestStreamEncodingErrorAbortsAfterFirstItem(t *testing.T) {
+	ass := assert.New(t)
+	rec := httptest.NewRecorder()
+	handler := JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(slices.Values([]float64{1, math.NaN()}))
+	})
+
+	ass.PanicsWithValue(http.ErrAbortHandler, func() {
+		handler(rec, httptest.NewRequest(http.MethodGet, "/n", nil))
+	})
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("[1", rec.Body.String())
+}
+
+func TestStreamFlushesPeriodically(t *testing.T) {
+	var seq iter.Seq[int] = func(yield func(int) bool) {
+		for i := range 2*streamFlushItems + 1 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	rec := &flushCounter{ResponseRecorder: httptest.NewRecorder()}
+
+	JSON(func(w http.ResponseWriter, r *http.Request) *Response { return OK(seq) })(rec, httptest.NewRequest(http.MethodGet, "/n", nil))
+
+	assert.Equal(t, 3, rec.flushes)
+}
+
+type flushCounter struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushCounter) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestStreamStopsWhenClientIsGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan int)
+	go func() {
+		ch <- 1
+		cancel()
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/n", nil).WithContext(ctx)
+	rec := streamTo(t, req, OK((<-chan int)(ch)).SetContentType(NDJSONContentType))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1\n", rec.Body.String())
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type encodedItem struct {
+	XMLName xml.Name  `json:"-" xml:"item"`
+	ID      int       `json:"id" xml:"id"`
+	Name    string    `json:"name" xml:"name"`
+	Tags    []string  `json:"tags" xml:"tags"`
+	At      time.Time `json:"at" xml:"at"`
+}
+
+func negotiate(t *testing.T, accept string, resp *Response) *httptest.ResponseRecorder {
+	t.Helper()
+	h := JSON(func(w http.ResponseWriter, r *http.Request) *Response { return resp })
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestNegotiationDefaultsToJSON(t *testing.T) {
+	ass := assert.New(t)
+
+	for _, accept := range []string{"", "*/*", "text/html,application/xhtml+xml,*/*;q=0.8", "application/*"} {
+		rec := negotiate(t, accept, OK(map[string]int{"id": 1}))
+		ass.Equal(http.StatusOK, rec.Code, accept)
+		ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"), accept)
+		ass.JSONEq(`{"id":1}`, rec.Body.String(), accept)
+	}
+}
+
+func TestNegotiationPicksByQuality(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := negotiate(t, "application/json;q=0.5, application/xml", OK(map[string]string{"name": "a & b"}))
+
+	ass.Equal("application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.Equal(xml.Header+"<response><name>a &amp; b</name></response>", rec.Body.String())
+	ass.Equal("Accept", rec.Header().Get("Vary"))
+}
+
+func TestNegotiationXMLStruct(t *testing.T) {
+	rec := negotiate(t, "text/xml", OK(encodedItem{ID: 1, Name: "one"}))
+
+	assert.Equal(t, "text/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "<item><id>1</id><name>one</name>")
+}
+
+func TestNegotiationCSV(t *testing.T) {
+	ass := assert.New(t)
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	items := []encodedItem{{ID: 1, Name: "one", Tags: []string{"a"}, At: at}, {ID: 2, Name: "two, three"}}
+	rec := negotiate(t, "text/csv", OK(items))
+
+	ass.Equal("text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.Equal("id,name,tags,at\n"+
+		"1,one,\"[\"\"a\"\"]\",2025-01-02T03:04:05Z\n"+
+		"2,\"two, three\",null,0001-01-01T00:00:00Z\n", rec.Body.String())
+
+	// A single struct has no CSV representation
+	rec = negotiate(t, "text/csv", OK(items[0]))
+	ass.Equal(http.StatusNotAcceptable, rec.Code)
+}
+
+func TestNegotiationCBORAndMessagePack(t *testing.T) {
+	ass := assert.New(t)
+	payload := map[string]any{"a": 1, "b": []any{true, nil, -2, "x"}, "c": 1.5}
+
+	rec := negotiate(t, "application/cbor", OK(payload))
+	ass.Equal("application/cbor", rec.Header().Get("Content-Type"))
+	// {"a": 1, "b": [true, null, -2, "x"], "c": 1.5}
+	ass.Equal("a3"+"6161"+"01"+"6162"+"84f5f62161"+"78"+"6163"+"fb3ff8000000000000", hex.EncodeToString(rec.Body.Bytes()))
+
+	rec = negotiate(t, "application/msgpack", OK(payload))
+	ass.Equal("application/msgpack", rec.Header().Get("Content-Type"))
+	ass.Equal("83"+"a161"+"01"+"a162"+"94c3c0fea178"+"a163"+"cb3ff8000000000000", hex.EncodeToString(rec.Body.Bytes()))
+}
+
+func TestNegotiationProtobuf(t *testing.T) {
+	ass := assert.New(t)
+	msg := wrapperspb.String("hello")
+
+	rec := negotiate(t, "application/x-protobuf", OK(msg))
+	ass.Equal("application/x-protobuf", rec.Header().Get("Content-Type"))
+	var decoded wrapperspb.StringValue
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &decoded))
+	ass.Equal("hello", decoded.GetValue())
+
+	rec = negotiate(t, "application/json", OK(msg))
+	ass.Equal(`"hello"`+"\n", rec.Body.String())
+
+	// Only proto messages have a protobuf representation
+	rec = negotiate(t, "application/x-protobuf", OK(map[string]int{"id": 1}))
+	ass.Equal(http.StatusNotAcceptable, rec.Code)
+}
+
+func TestNegotiationNotAcceptable(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := negotiate(t, "image/png", OK(map[string]int{"id": 1}))
+	ass.Equal(http.StatusNotAcceptable, rec.Code)
+	ass.Contains(rec.Body.String(), CodeNotAcceptable)
+	ass.Equal("Accept", rec.Header().Get("Vary"))
+
+	rec = negotiate(t, "application/json;q=0, */*", OK(map[string]int{"id": 1}))
+	ass.Equal("application/xml; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	// Errors keep their status, in JSON
+	rec = negotiate(t, "image/png", NotFound("item 1"))
+	ass.Equal(http.StatusNotFound, rec.Code)
+	ass.JSONEq(`{"code":"not_found","message":"not found","details":"item 1"}`, rec.Body.String())
+	ass.Equal("Accept", rec.Header().Get("Vary"))
+}
+
+func TestSetContentTypeForcesTheRepresentation(t *testing.T) {
+	rec := negotiate(t, "application/json", OK([]encodedItem{{ID: 1, Name: "one"}}).SetContentType("text/csv"))
+
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "id,name,tags,at\n")
+}
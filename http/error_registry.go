@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorRegistry maps errors returned by handlers to responses, safe for concurrent use.
+//
+// Behavior:
+//   - Mappings are tried from the most recently registered, so that applications
+//     can override the built-in ones. Unmapped errors answer 500 (InternalError payload).
+//   - The response details are the public message given at registration, an empty message
+//     uses the message of the target itself (e.g. "not found"), never the wrapping context.
+//     RegisterExposed sends err.Error() instead, for errors written for clients.
+//   - Built-in mappings:
+//   - *Response is written as is, FieldErrors answer 422 listing the fields;
+//   - ErrBadRequest, ErrUnauthorized, ErrForbidden, ErrNotFound, ErrConflict, ErrUnprocessable,
+//     ErrTooManyRequests and ErrUnavailable answer their status with their own message as details;
+//   - context.DeadlineExceeded answers 504, badger.ErrKeyNotFound answers 404;
+//   - gRPC status errors answer the matching HTTP status (NotFound → 404, Unavailable → 503...),
+//     with the message of the innermost status as details for client errors only.
+//
+// Example usage:
+//
+//	DefaultErrorRegistry.Register(ErrInsufficientFunds, http.StatusPaymentRequired, "insufficient funds")
+//	RegisterErrorType(DefaultErrorRegistry, func(err *QuotaError) *Response {
+//		return TooManyRequests("quota exceeded").AddHeader("Retry-After", err.RetryAfter)
+//	})
+type ErrorRegistry struct {
+	mu      sync.RWMutex
+	mappers []func(err error) *Response
+}
+
+// DefaultErrorRegistry is used by Typed and when no registry is configured.
+var DefaultErrorRegistry = NewErrorRegistry()
+
+// NewErrorRegistry creates a registry with the built-in mappings.
+func NewErrorRegistry() *ErrorRegistry {
+	reg := &ErrorRegistry{}
+
+	reg.RegisterFunc(grpcErrorResponse)
+	reg.Register(badger.ErrKeyNotFound, http.StatusNotFound, "resource not found")
+	reg.Register(context.DeadlineExceeded, http.StatusGatewayTimeout, "deadline exceeded")
+	reg.Register(ErrBadRequest, http.StatusBadRequest, "")
+	reg.Register(ErrUnauthorized, http.StatusUnauthorized, "")
+	reg.Register(ErrForbidden, http.StatusForbidden, "")
+	reg.Register(ErrNotFound, http.StatusNotFound, "")
+	reg.Register(ErrConflict, http.StatusConflict, "")
+	reg.Register(ErrUnprocessable, http.StatusUnprocessableEntity, "")
+	reg.Register(ErrTooManyRequests, http.StatusTooManyRequests, "")
+	reg.Register(ErrUnavailable, http.StatusServiceUnavailable, "")
+	RegisterErrorType(reg, func(errs FieldErrors) *Response {
+		return fieldErrorsResponse(http.StatusUnprocessableEntity, errs)
+	})
+	RegisterErrorType(reg, func(resp *Response) *Response {
+		return resp
+	})
+	return reg
+}
+
+// Register maps the errors matching target (errors.Is) to a status code and a public message,
+// target.Error() when empty.
+func (reg *ErrorRegistry) Register(target error, statusCode int, message string) {
+	if message == "" {
+		message = target.Error()
+	}
+	reg.RegisterFunc(func(err error) *Response {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return errorWithStatus(statusCode, message)
+	})
+}
+
+// RegisterExposed maps the errors matching target (errors.Is) to a status code with err.Error()
+// as details, wrapping context included.
+// ⚠️ Only for errors whose whole chain is written for clients, e.g. fmt.Errorf("email %q: %w", email, ErrTaken).
+func (reg *ErrorRegistry) RegisterExposed(target error, statusCode int) {
+	reg.RegisterFunc(func(err error) *Response {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return errorWithStatus(statusCode, err.Error())
+	})
+}
+
+// RegisterFunc adds a mapping returning nil for the errors it does not handle.
+func (reg *ErrorRegistry) RegisterFunc(fn func(err error) *Response) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.mappers = append(reg.mappers, fn)
+}
+
+// RegisterErrorType maps the errors of type E (errors.As) with fn.
+// It is a function since Go methods cannot have type parameters.
+func RegisterErrorType[E error](reg *ErrorRegistry, fn func(err E) *Response) {
+	reg.RegisterFunc(func(err error) *Response {
+		var target E
+		if !errors.As(err, &target) {
+			return nil
+		}
+		return fn(target)
+	})
+}
+
+// Response returns the response of err, or nil when err is nil.
+func (reg *ErrorRegistry) Response(err error) *Response {
+	if err == nil {
+		return nil
+	}
+
+	reg.mu.RLock()
+	mappers := slices.Clone(reg.mappers)
+	reg.mu.RUnlock()
+
+	for _, mapper := range slices.Backward(mappers) {
+		if resp := mapper(err); resp != nil {
+			return resp
+		}
+	}
+	return InternalError("unexpected error")
+}
+
+// ErrorHandler is a Handler that can fail with an error.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request) (*Response, error)
+
+// JSON adapts an ErrorHandler like the JSON function, returned errors are mapped with the registry.
+// The original error is logged at ERROR level for server errors and DEBUG level for client errors,
+// it never reaches the client.
+//
+// Example usage:
+//
+//	mux.HandleFunc("GET /users/{id}", DefaultErrorRegistry.JSON(logger, func(w http.ResponseWriter, r *http.Request) (*Response, error) {
+//		user, err := store.Get(r.Context(), r.PathValue("id"))
+//		if err != nil {
+//			return nil, err
+//		}
+//		return OK(user), nil
+//	}))
+func (reg *ErrorRegistry) JSON(logger *slog.Logger, h ErrorHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, err := h(w, r)
+		if err != nil {
+			resp = reg.Response(err)
+			logMappedError(logger, r, err, resp)
+		}
+		respond(w, r, resp)
+	}
+}
+
+// logMappedError logs the original error of a mapped response.
+func logMappedError(logger *slog.Logger, r *http.Request, err error, resp *Response) {
+	if logger == nil {
+		return
+	}
+	level := slog.LevelDebug
+	if resp.StatusCode >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	loggerWithRequestID(logger, r).Log(r.Context(), level, "handler error",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", resp.StatusCode),
+		slog.Any("error", err),
+	)
+}
+
+// grpcStatusCodes follows the mapping of the gRPC-HTTP gateways.
+var grpcStatusCodes = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// grpcErrorResponse maps gRPC status errors, the message of server errors is not exposed.
+func grpcErrorResponse(err error) *Response {
+	// status.FromError would use err.Error() as the message of a wrapped status
+	var withStatus interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &withStatus) {
+		return nil
+	}
+	s := withStatus.GRPCStatus()
+	if s.Code() == codes.OK {
+		return nil
+	}
+	statusCode, ok := grpcStatusCodes[s.Code()]
+	if !ok {
+		statusCode = http.StatusInternalServerError
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		return errorWithStatus(statusCode, "upstream error")
+	}
+	if statusCode == 499 {
+		return &Response{
+			Payload:    map[string]string{"code": "canceled", "message": "client closed request", "details": "request canceled"},
+			StatusCode: statusCode,
+		}
+	}
+	return errorWithStatus(statusCode, s.Message())
+}
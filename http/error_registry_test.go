@@ -0,0 +1,129 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errInsufficientFunds = errors.New("insufficient funds on account 1234")
+
+type quotaError struct {
+	RetryAfter string
+}
+
+func (e *quotaError) Error() string {
+	return "quota exceeded"
+}
+
+func TestErrorRegistryBuiltInMappings(t *testing.T) {
+	ass := assert.New(t)
+	reg := NewErrorRegistry()
+
+	cases := []struct {
+		err     error
+		status  int
+		details string
+	}{
+		{fmt.Errorf("load user 42 from badger: %w", ErrNotFound), http.StatusNotFound, "not found"},
+		{fmt.Errorf("load: %w", badger.ErrKeyNotFound), http.StatusNotFound, "resource not found"},
+		{fmt.Errorf("call: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "deadline exceeded"},
+		{status.Error(codes.InvalidArgument, "name is empty"), http.StatusBadRequest, "name is empty"},
+		{status.Error(codes.Internal, "db password is wrong"), http.StatusInternalServerError, "upstream error"},
+		{fmt.Errorf("call users-service at 10.0.0.7: %w", status.Error(codes.NotFound, "user not found")), http.StatusNotFound, "user not found"},
+		{errors.New("boom"), http.StatusInternalServerError, "unexpected error"},
+	}
+	for _, c := range cases {
+		resp := reg.Response(c.err)
+		ass.Equal(c.status, resp.StatusCode, c.err.Error())
+		ass.Equal(c.details, resp.Payload.(map[string]string)["details"], c.err.Error())
+	}
+
+	ass.Nil(reg.Response(nil))
+	teapot := &Response{StatusCode: http.StatusTeapot}
+	ass.Same(teapot, reg.Response(fmt.Errorf("wrapped: %w", teapot)))
+}
+
+func TestErrorRegistryCustomMappings(t *testing.T) {
+	ass := assert.New(t)
+	reg := NewErrorRegistry()
+
+	reg.Register(errInsufficientFunds, http.StatusPaymentRequired, "insufficient funds")
+	RegisterErrorType(reg, func(err *quotaError) *Response {
+		return TooManyRequests("quota exceeded").AddHeader("Retry-After", err.RetryAfter)
+	})
+	// Overrides the built-in mapping
+	reg.Register(ErrNotFound, http.StatusGone, "gone")
+
+	resp := reg.Response(fmt.Errorf("charge: %w", errInsufficientFunds))
+	ass.Equal(http.StatusPaymentRequired, resp.StatusCode)
+	ass.Equal("insufficient funds", resp.Payload.(map[string]string)["details"])
+
+	resp = reg.Response(fmt.Errorf("call: %w", &quotaError{RetryAfter: "30"}))
+	ass.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	ass.Equal("30", resp.header["Retry-After"])
+
+	ass.Equal(http.StatusGone, reg.Response(ErrNotFound).StatusCode)
+}
+
+func TestErrorRegistryJSONLogsButNeverLeaks(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+	reg := NewErrorRegistry()
+	reg.Register(errInsufficientFunds, http.StatusPaymentRequired, "insufficient funds")
+
+	h := reg.JSON(logger, func(w http.ResponseWriter, r *http.Request) (*Response, error) {
+		if r.URL.Query().Get("fail") == "internal" {
+			return nil, errors.New("connection to 10.0.0.1 refused")
+		}
+		return nil, errInsufficientFunds
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/charge", nil))
+	ass.Equal(http.StatusPaymentRequired, rec.Code)
+	ass.JSONEq(`{"code":"payment_required","message":"payment required","details":"insufficient funds"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/charge?fail=internal", nil))
+	ass.Equal(http.StatusInternalServerError, rec.Code)
+	ass.NotContains(rec.Body.String(), "10.0.0.1")
+	ass.Contains(buf.String(), "10.0.0.1")
+}
+
+func TestErrorRegistryJSONWritesSuccess(t *testing.T) {
+	h := DefaultErrorRegistry.JSON(nil, func(w http.ResponseWriter, r *http.Request) (*Response, error) {
+		return OK(map[string]int{"id": 1}), nil
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1}`, rec.Body.String())
+}
+
+func TestErrorRegistryRegisterExposed(t *testing.T) {
+	ass := assert.New(t)
+
+	errTaken := errors.New("is already taken")
+	reg := NewErrorRegistry()
+	reg.RegisterExposed(errTaken, http.StatusConflict)
+
+	resp := reg.Response(fmt.Errorf("email bob@example.com %w", errTaken))
+	ass.Equal(http.StatusConflict, resp.StatusCode)
+	ass.Equal("email bob@example.com is already taken", resp.Payload.(map[string]string)["details"])
+}
@@ -0,0 +1,277 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// DefaultIdempotencyHeader is the header carrying the idempotency key.
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+const (
+	badgerIdempotencyPrefix = "idempotency:"
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyConfig configures IdempotencyMiddleware.
+type IdempotencyConfig struct {
+	// Header carrying the key (default: Idempotency-Key)
+	Header string
+	// Methods concerned by the middleware (default: POST, PUT, PATCH, DELETE)
+	Methods []string
+	// TTL is how long a completed response is replayed (default: 24h)
+	TTL time.Duration
+	// LockTTL bounds how long a request is considered in flight,
+	// in case the process dies before storing the response (default: 1 minute)
+	LockTTL time.Duration
+	// Scope partitions keys, e.g. per client with KeyByJWTSubject, so that clients
+	// cannot replay each other's responses (default: no partition)
+	Scope KeyFunc
+}
+
+// idempotencyRecord is the value stored in Badger for a key.
+type idempotencyRecord struct {
+	Completed   bool        `json:"completed"`
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// IdempotencyMiddleware returns an HTTP middleware making unsafe requests idempotent
+// when they carry an Idempotency-Key header.
+//
+// Behavior:
+//   - Fingerprints the request (method, path, query and body).
+//   - First request: marks the key in flight, runs the handler and stores its final
+//     response (status, headers written by the handler, body) for TTL. Server errors (5xx)
+//     and panics release the key so that the client can retry.
+//   - Retry with the same fingerprint: replays the stored response with an
+//     "Idempotent-Replayed: true" header, the handler is not called.
+//   - Retry while the first request is still running: 409 (Conflict payload).
+//   - Same key with a different fingerprint: 422 (UnprocessableEntity payload).
+//   - Store failures answer 503 (ServiceUnavailable payload) rather than risking a duplicate,
+//     and are logged at ERROR level (slog.Default() when logger is nil).
+//
+// Example usage:
+//
+//	db, err := database.LoadBadger(database.DefaultPath)
+//	...
+//	http.Handle("/orders", IdempotencyMiddleware(logger, db, IdempotencyConfig{})(JSON(createOrder)))
+func IdempotencyMiddleware(logger *slog.Logger, db *badger.DB, cfg IdempotencyConfig) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	header := cfg.Header
+	if header == "" {
+		header = DefaultIdempotencyHeader
+	}
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	lockTTL := cfg.LockTTL
+	if lockTTL <= 0 {
+		lockTTL = time.Minute
+	}
+
+	concerned := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		concerned[m] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(header)
+			if key == "" || !concerned[r.Method] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				respondWithJSON(w, BadRequest("idempotency key too long"))
+				return
+			}
+
+			body, err := readLimitedBody(r.Body, bodyLimitsFromContext(r.Context()).MaxBytes)
+			if errors.Is(err, errBodyTooLarge) {
+				respondWithJSON(w, RequestEntityTooLarge("body too large for an idempotent request"))
+				return
+			}
+			if err != nil {
+				respondWithJSON(w, BadRequest("unreadable body"))
+				return
+			}
+			r.Body = readCloser{Reader: bytes.NewReader(body), Closer: r.Body}
+
+			storeKey := badgerIdempotencyPrefix + key
+			if cfg.Scope != nil {
+				storeKey = badgerIdempotencyPrefix + cfg.Scope(r) + "|" + key
+			}
+			fingerprint := requestFingerprint(r, body)
+			log := loggerWithRequestID(logger, r)
+
+			existing, err := lockIdempotencyKey(db, []byte(storeKey), fingerprint, lockTTL)
+			if err != nil {
+				log.Error("idempotency store error",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Any("error", err),
+				)
+				respondWithJSON(w, ServiceUnavailable("idempotency store unavailable"))
+				return
+			}
+
+			switch {
+			case existing == nil:
+				// First request, the key is now in flight
+
+			case existing.Fingerprint != fingerprint:
+				respondWithJSON(w, UnprocessableEntity("idempotency key reused with a different request"))
+				return
+
+			case !existing.Completed:
+				respondWithJSON(w, Conflict("a request with the same idempotency key is in progress"))
+				return
+
+			default:
+				replayIdempotentResponse(w, existing)
+				return
+			}
+
+			// Headers set by outer middlewares (request ID, rate limit, CSP nonce) belong to this request only
+			before := w.Header().Clone()
+			cw := &captureWriter{responseRecorder: newResponseRecorder(w)}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				// Panic or server error: release the key so that the client can retry
+				if err := deleteIdempotencyKey(db, []byte(storeKey)); err != nil {
+					log.Warn("idempotency key release error", slog.Any("error", err))
+				}
+			}()
+
+			next.ServeHTTP(cw, r)
+
+			if cw.status >= http.StatusInternalServerError {
+				return
+			}
+			record := idempotencyRecord{
+				Completed:   true,
+				Fingerprint: fingerprint,
+				Status:      cw.status,
+				Header:      handlerHeader(before, w.Header()),
+				Body:        cw.body.Bytes(),
+			}
+			if err := storeIdempotencyRecord(db, []byte(storeKey), record, ttl); err != nil {
+				log.Error("idempotency store error",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Any("error", err),
+				)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// captureWriter keeps a copy of the body written to the client.
+type captureWriter struct {
+	*responseRecorder
+	body bytes.Buffer
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	n, err := cw.responseRecorder.Write(b)
+	cw.body.Write(b[:n])
+	return n, err
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lockIdempotencyKey returns the existing record of key, or creates an in-flight one and returns nil.
+// Concurrent requests conflict in Badger, the losing transaction is retried and sees the record.
+func lockIdempotencyKey(db *badger.DB, key []byte, fingerprint string, lockTTL time.Duration) (*idempotencyRecord, error) {
+	var existing *idempotencyRecord
+
+	var err error
+	for range badgerMaxRetries {
+		existing = nil
+		err = db.Update(func(txn *badger.Txn) error {
+			item, err := txn.Get(key)
+			if err == nil {
+				return item.Value(func(v []byte) error {
+					existing = &idempotencyRecord{}
+					return json.Unmarshal(v, existing)
+				})
+			}
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+
+			value, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+			if err != nil {
+				return err
+			}
+			return txn.SetEntry(badger.NewEntry(key, value).WithTTL(lockTTL))
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return existing, err
+		}
+	}
+	return nil, err
+}
+
+func storeIdempotencyRecord(db *badger.DB, key []byte, record idempotencyRecord, ttl time.Duration) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry(key, value).WithTTL(ttl))
+	})
+}
+
+func deleteIdempotencyKey(db *badger.DB, key []byte) error {
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+// handlerHeader returns a copy of the headers added or changed since before.
+func handlerHeader(before, after http.Header) http.Header {
+	header := make(http.Header)
+	for k, v := range after {
+		if !slices.Equal(before[k], v) {
+			header[k] = slices.Clone(v)
+		}
+	}
+	return header
+}
+
+func replayIdempotentResponse(w http.ResponseWriter, record *idempotencyRecord) {
+	copyHeader(w.Header(), record.Header)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.Status)
+	_, _ = w.Write(record.Body)
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestBadger(t *testing.T) *badger.DB {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLoggingLevel(badger.ERROR))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DefaultIdempotencyHeader, key)
+	return req
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+
+	var calls atomic.Int32
+	handler := IdempotencyMiddleware(logger, openTestBadger(t), IdempotencyConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		n := calls.Add(1)
+		return Created(map[string]int32{"order": n}).AddHeader("Location", "/orders/1")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{"item":"book"}`))
+	ass.Equal(http.StatusCreated, rec.Code)
+	ass.Empty(rec.Header().Get("Idempotent-Replayed"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{"item":"book"}`))
+	ass.Equal(http.StatusCreated, rec.Code)
+	ass.Equal("true", rec.Header().Get("Idempotent-Replayed"))
+	ass.Equal("/orders/1", rec.Header().Get("Location"))
+	ass.JSONEq(`{"order":1}`, rec.Body.String())
+	ass.Equal(int32(1), calls.Load())
+
+	// Another key runs the handler again
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-2", `{"item":"book"}`))
+	ass.JSONEq(`{"order":2}`, rec.Body.String())
+}
+
+func TestIdempotencyFingerprintMismatch(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := IdempotencyMiddleware(nil, openTestBadger(t), IdempotencyConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return Created("ok")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-1", `{"item":"book"}`))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{"item":"pen"}`))
+	ass.Equal(http.StatusUnprocessableEntity, rec.Code)
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("unprocessable entity", body["message"])
+}
+
+func TestIdempotencyInFlightConflict(t *testing.T) {
+	ass := assert.New(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := IdempotencyMiddleware(nil, openTestBadger(t), IdempotencyConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		close(started)
+		<-release
+		return Created("ok")
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), idempotentRequest("key-1", `{}`))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{}`))
+	ass.Equal(http.StatusConflict, rec.Code)
+
+	close(release)
+	<-done
+}
+
+func TestIdempotencyServerErrorReleasesKey(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	handler := IdempotencyMiddleware(nil, openTestBadger(t), IdempotencyConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		if calls.Add(1) == 1 {
+			return InternalError("database down")
+		}
+		return Created("ok")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{}`))
+	ass.Equal(http.StatusInternalServerError, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{}`))
+	ass.Equal(http.StatusCreated, rec.Code)
+	ass.Empty(rec.Header().Get("Idempotent-Replayed"))
+	ass.Equal(int32(2), calls.Load())
+}
+
+func TestIdempotencyIgnoresSafeMethodsAndMissingKey(t *testing.T) {
+	ass := assert.New(t)
+
+	var calls atomic.Int32
+	handler := IdempotencyMiddleware(nil, openTestBadger(t), IdempotencyConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(DefaultIdempotencyHeader, "key-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	ass.Equal(int32(4), calls.Load())
+}
+
+func TestIdempotencyReplayKeepsPerRequestHeaders(t *testing.T) {
+	ass := assert.New(t)
+
+	var requests atomic.Int32
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(DefaultRequestIDHeader, fmt.Sprintf("req-%d", requests.Add(1)))
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := outer(IdempotencyMiddleware(nil, openTestBadger(t), IdempotencyConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return Created("ok").AddHeader("Location", "/orders/1")
+	})))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{}`))
+	ass.Equal("req-1", rec.Header().Get(DefaultRequestIDHeader))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, idempotentRequest("key-1", `{}`))
+	ass.Equal("true", rec.Header().Get("Idempotent-Replayed"))
+	ass.Equal("req-2", rec.Header().Get(DefaultRequestIDHeader))
+	ass.Equal("/orders/1", rec.Header().Get("Location"))
+}
+
+func TestIdempotencyStoreFailure(t *testing.T) {
+	ass := assert.New(t)
+
+	db := openTestBadger(t)
+	require.NoError(t, db.Close())
+
+	var calls atomic.Int32
+	handler := IdempotencyMiddleware(nil, db, IdempotencyConfig{})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		calls.Add(1)
+		return Created(nil)
+	}))
+
+	rec := httptest.NewRecorder()
+	ass.NotPanics(func() { handler.ServeHTTP(rec, idempotentRequest("key-1", `{}`)) })
+	ass.Equal(http.StatusServiceUnavailable, rec.Code)
+	ass.Zero(calls.Load())
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/mama165/sdk-go/metrics"
+)
+
+// unmatchedRoute labels requests that no ServeMux pattern matched.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware returns an HTTP middleware recording server metrics in reg:
+//
+//   - http_server_requests_total{method,route,status}: counter
+//   - http_server_requests_in_flight{method}: gauge
+//   - http_server_request_duration_seconds{method,route,status}: histogram
+//   - http_server_response_size_bytes{method,route,status}: histogram
+//
+// Labels are bounded to avoid cardinality blowups: route is the ServeMux pattern
+// ("GET /users/{id}"), never the raw path, status is the class ("2xx") and
+// unknown methods are reported as "OTHER".
+//
+// The pattern is set by the ServeMux on the request it routes, which is a copy as soon as
+// a middleware in between calls r.WithContext (RequestIDMiddleware, TimeoutMiddleware...).
+// Wrap the mux with RecordRoute so that the pattern is passed back through the context.
+// Expose reg.Handler() on a route.
+//
+// Example usage:
+//
+//	reg := metrics.NewRegistry()
+//	mux.Handle("GET /metrics", reg.Handler())
+//	chain := NewChain(MetricsMiddleware(reg), RequestIDMiddleware(RequestIDConfig{}))
+//	http.ListenAndServe(":8080", chain.Then(RecordRoute(mux)))
+func MetricsMiddleware(reg *metrics.Registry) func(http.Handler) http.Handler {
+	requests := reg.NewCounterVec("http_server_requests_total",
+		"Total number of HTTP requests.", "method", "route", "status")
+	inFlight := reg.NewGaugeVec("http_server_requests_in_flight",
+		"Number of HTTP requests being served.", "method")
+	duration := reg.NewHistogramVec("http_server_request_duration_seconds",
+		"Duration of HTTP requests in seconds.", metrics.DefaultBuckets, "method", "route", "status")
+	size := reg.NewHistogramVec("http_server_response_size_bytes",
+		"Size of HTTP response bodies in bytes.", metrics.SizeBuckets, "method", "route", "status")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := normalizeMethod(r.Method)
+			gauge := inFlight.With(method)
+			gauge.Inc()
+			defer gauge.Dec()
+
+			holder := &routeHolder{}
+			r = r.WithContext(context.WithValue(r.Context(), routeKey{}, holder))
+
+			start := time.Now()
+			rw := newResponseRecorder(w)
+			next.ServeHTTP(rw, r)
+
+			route := r.Pattern
+			if p := holder.pattern.Load(); p != nil {
+				route = *p
+			}
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(rw.status/100) + "xx"
+
+			requests.With(method, route, status).Inc()
+			duration.With(method, route, status).Observe(time.Since(start).Seconds())
+			size.With(method, route, status).Observe(float64(rw.written))
+		})
+	}
+}
+
+// routeHolder receives the ServeMux pattern of a request from the handlers,
+// atomically since TimeoutMiddleware runs them in their own goroutine.
+type routeHolder struct {
+	pattern atomic.Pointer[string]
+}
+
+type routeKey struct{}
+
+// RecordRoute wraps a ServeMux so that MetricsMiddleware labels its requests with the matched
+// pattern whatever the middlewares in between.
+func RecordRoute(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if holder, ok := r.Context().Value(routeKey{}).(*routeHolder); ok && pattern != "" {
+			holder.pattern.Store(&pattern)
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mama165/sdk-go/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := metrics.NewRegistry()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", reg.Handler())
+	mux.Handle("GET /users/{id}", JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		if r.PathValue("id") == "0" {
+			return NotFound("no user")
+		}
+		return OK("user")
+	}))
+	handler := MetricsMiddleware(reg)(mux)
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/unknown"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/users/1", nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	ass.Equal(metrics.ContentType, rec.Header().Get("Content-Type"))
+	ass.Contains(body, `http_server_requests_total{method="GET",route="GET /users/{id}",status="2xx"} 2`)
+	ass.Contains(body, `http_server_requests_total{method="GET",route="GET /users/{id}",status="4xx"} 1`)
+	ass.Contains(body, `http_server_requests_total{method="GET",route="unmatched",status="4xx"} 1`)
+	ass.Contains(body, `http_server_requests_total{method="OTHER",route="unmatched",status="4xx"} 1`)
+	ass.Contains(body, `http_server_request_duration_seconds_count{method="GET",route="GET /users/{id}",status="2xx"} 2`)
+	ass.Contains(body, `http_server_response_size_bytes_sum{method="GET",route="GET /users/{id}",status="2xx"} 14`)
+	// The scrape itself is in flight
+	ass.Contains(body, `http_server_requests_in_flight{method="GET"} 1`)
+	ass.NotContains(body, "/users/1")
+}
+
+func TestMetricsMiddlewareCanBeCreatedTwice(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := metrics.NewRegistry()
+	ass.NotPanics(func() {
+		MetricsMiddleware(reg)
+		MetricsMiddleware(reg)
+	})
+}
+
+func TestMetricsMiddlewareThroughRequestCopies(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := metrics.NewRegistry()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", reg.Handler())
+	mux.Handle("GET /users/{id}", JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK("user")
+	}))
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	// RequestIDMiddleware routes a copy of the request to the mux
+	chain := NewChain(MetricsMiddleware(reg), RequestIDMiddleware(RequestIDConfig{}))
+	handler := chain.Then(mux)
+	recorded := chain.Then(RecordRoute(mux))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	recorded.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	recorded.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	ass.Contains(body, `http_server_requests_total{method="GET",route="GET /users/{id}",status="2xx"} 1`)
+	// The pattern set on the copy is lost without RecordRoute
+	ass.Contains(body, `http_server_requests_total{method="GET",route="unmatched",status="2xx"} 1`)
+	ass.Contains(body, `http_server_requests_total{method="GET",route="GET /health",status="2xx"} 1`)
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
@@ -15,20 +15,23 @@ const (
 	maxObservedBytes = 8 << 10 // 8 KB
 )
 
-// LogJSONBodyMiddleware returns an HTTP middleware that logs JSON request bodies.
+// LogJSONBodyMiddleware returns an HTTP middleware that logs request bodies.
 //
 // Behavior:
-//   - Only processes requests with Content-Type starting with "application/json".
-//   - Skips empty bodies or non-JSON requests.
+//   - Processes requests whose Content-Type has a decoder in DefaultBodyDecoders:
+//     JSON (and "+json" suffix types), form, multipart, XML and NDJSON.
+//   - Skips empty bodies or unsupported content types.
 //   - In Dev/Test (logger enabled at DEBUG level):
-//   - Reads and decodes the JSON body.
+//   - Reads and decodes the body within the BodyLimits set by BodyLimitMiddleware
+//     (DefaultBodyLimits otherwise), larger or too complex bodies are not logged.
 //   - Sanitizes sensitive fields (password, token, etc.).
-//   - Logs the full JSON payload for debugging.
+//   - Logs the full payload for debugging (file contents of multipart bodies are never logged).
 //   - In Production (logger level < DEBUG):
 //   - Reads up to maxObservedBytes (8 KB) of the body.
 //   - Computes an SHA-256 hash of the body.
 //   - Logs minimal metadata without exposing sensitive data.
 //   - Restores the request body so the next handler can consume it.
+//   - Adds the "request_id" attribute when RequestIDMiddleware runs before it.
 //     ⚠️ Do not use DEBUG logging in production for sensitive data.
 //     ⚠️ This middleware is transparent and does not modify request behavior.
 //
@@ -36,10 +39,21 @@ const (
 //
 //	http.Handle("/api", LogJSONBodyMiddleware(logger)(myHandler))
 func LogJSONBodyMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return LogBodyMiddleware(logger, DefaultBodyDecoders())
+}
+
+// LogBodyMiddleware behaves like LogJSONBodyMiddleware with a custom set of decoders keyed by media type.
+//
+// Example usage:
+//
+//	decoders := DefaultBodyDecoders()
+//	decoders["application/vnd.custom"] = myDecoder
+//	http.Handle("/api", LogBodyMiddleware(logger, decoders)(myHandler))
+func LogBodyMiddleware(logger *slog.Logger, decoders map[string]BodyDecoder) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ct := r.Header.Get("Content-Type")
-			if !strings.HasPrefix(ct, "application/json") {
+			decode, params, ok := findBodyDecoder(decoders, r.Header.Get("Content-Type"))
+			if !ok {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -49,57 +63,86 @@ func LogJSONBodyMiddleware(logger *slog.Logger) func(http.Handler) http.Handler
 				return
 			}
 
+			log := loggerWithRequestID(logger, r)
+
 			// 🔀 Only for dev & test
-			if logger.Enabled(r.Context(), slog.LevelDebug) {
-				logJSONDebug(logger, r)
+			if log.Enabled(r.Context(), slog.LevelDebug) {
+				logBodyDebug(log, r, decode, params)
 				next.ServeHTTP(w, r)
 				return
 			}
-			logJSONSafe(logger, r)
+			logBodySafe(log, r)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-func logJSONDebug(logger *slog.Logger, r *http.Request) {
-	var buf bytes.Buffer
-	tee := io.TeeReader(r.Body, &buf)
-
-	var payload any
-	err := json.NewDecoder(tee).Decode(&payload)
+func logBodyDebug(logger *slog.Logger, r *http.Request, decode BodyDecoder, params map[string]string) {
+	limits := bodyLimitsFromContext(r.Context())
 
-	switch {
-	case err == io.EOF:
-		logger.Debug("incoming request",
+	body, err := readLimitedBody(r.Body, limits.MaxBytes)
+	// restore body (transparent), including what was left unread past the limit
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if errors.Is(err, errBodyTooLarge) {
+		logger.Warn("request body too large to be logged",
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
+			slog.Int64("max_bytes", limits.MaxBytes),
 		)
-
-	case err != nil:
-		logger.Error("invalid JSON body",
+		return
+	}
+	if err != nil {
+		logger.Warn("request body read error",
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
 			slog.Any("error", err),
 		)
+		return
+	}
 
-	default:
-		Sanitize(payload)
+	if len(bytes.TrimSpace(body)) == 0 {
 		logger.Debug("incoming request",
 			slog.String("method", r.Method),
 			slog.String("path", r.URL.Path),
-			slog.Any("body", payload),
 		)
+		return
 	}
 
-	r.Body = io.NopCloser(&buf)
+	payload, err := decodeWithinLimits(r, body, decode, params, limits)
+	if err != nil {
+		logger.Error("invalid request body",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("content_type", r.Header.Get("Content-Type")),
+			slog.Any("error", err),
+		)
+		return
+	}
+
+	Sanitize(payload)
+	logger.Debug("incoming request",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Any("body", payload),
+	)
 }
 
-func logJSONSafe(logger *slog.Logger, r *http.Request) {
-	limited := io.LimitReader(r.Body, maxObservedBytes)
+// decodeWithinLimits checks the JSON complexity limits before decoding,
+// so that a deeply nested or huge document is never materialized.
+func decodeWithinLimits(r *http.Request, body []byte, decode BodyDecoder, params map[string]string, limits BodyLimits) (any, error) {
+	if isJSONContentType(r.Header.Get("Content-Type")) {
+		if err := checkJSONLimits(body, limits); err != nil {
+			return nil, err
+		}
+	}
+	return decode(body, params)
+}
+
+func logBodySafe(logger *slog.Logger, r *http.Request) {
+	var observed bytes.Buffer
 	hasher := sha256.New()
-	hash := hex.EncodeToString(hasher.Sum(nil))
 
-	read, err := io.Copy(hasher, limited)
+	read, err := io.Copy(io.MultiWriter(hasher, &observed), io.LimitReader(r.Body, maxObservedBytes))
 	if err != nil {
 		logger.Warn("request body read error",
 			slog.Any("error", err),
@@ -111,12 +154,18 @@ func logJSONSafe(logger *slog.Logger, r *http.Request) {
 			slog.String("content_type", r.Header.Get("Content-Type")),
 			slog.Int64("content_length", r.ContentLength),
 			slog.Int64("observed_bytes", read),
-			slog.String("body_sha256", hash),
+			slog.String("body_sha256", hex.EncodeToString(hasher.Sum(nil))),
 		)
 	}
 
 	// restore body (transparent)
-	r.Body = io.NopCloser(io.MultiReader(io.LimitReader(strings.NewReader(""), 0), r.Body))
+	r.Body = readCloser{Reader: io.MultiReader(&observed, r.Body), Closer: r.Body}
+}
+
+// readCloser pairs a reader with the Closer of the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 func Sanitize(v any) {
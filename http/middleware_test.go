@@ -3,6 +3,7 @@ package http
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"log/slog"
 	"mime/multipart"
 	"net/http"
@@ -101,7 +102,7 @@ func TestLogWithBodyError(t *testing.T) {
 	ass.Equal(http.StatusOK, w.Code)
 }
 
-func TestMultipartIsIgnored(t *testing.T) {
+func TestMultipartLogsFieldsAndFileMetadata(t *testing.T) {
 	ass := assert.New(t)
 
 	var buf bytes.Buffer
@@ -109,15 +110,56 @@ func TestMultipartIsIgnored(t *testing.T) {
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
-	defer writer.Close()
-	_, err := writer.CreateFormField("file")
+	ass.NoError(writer.WriteField("username", "john"))
+	ass.NoError(writer.WriteField("password", "secret"))
+	file, err := writer.CreateFormFile("avatar", "avatar.png")
 	ass.NoError(err)
+	_, err = file.Write([]byte("binary-content"))
+	ass.NoError(err)
+	ass.NoError(writer.Close())
 
 	r := httptest.NewRequest(http.MethodPost, "/upload", body)
 	r.Header.Set("Content-Type", writer.FormDataContentType())
 
 	w := httptest.NewRecorder()
 
+	LogJSONBodyMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The next handler can still parse the form
+		ass.NoError(r.ParseMultipartForm(1 << 20))
+		ass.Equal("john", r.FormValue("username"))
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, r)
+
+	ass.Equal(http.StatusOK, w.Code)
+	ass.NotContains(buf.String(), "binary-content")
+
+	var logEntry map[string]any
+	ass.NoError(json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &logEntry))
+
+	bodyField := logEntry["body"].(map[string]any)
+	fields := bodyField["fields"].(map[string]any)
+	ass.Equal("john", fields["username"])
+	ass.Equal("*****", fields["password"])
+
+	files := bodyField["files"].([]any)
+	ass.Len(files, 1)
+	f := files[0].(map[string]any)
+	ass.Equal("avatar", f["field"])
+	ass.Equal("avatar.png", f["filename"])
+	ass.Equal(float64(len("binary-content")), f["size"])
+}
+
+func TestUnsupportedContentTypeIsIgnored(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelDebug)
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("raw bytes"))
+	r.Header.Set("Content-Type", "application/octet-stream")
+
+	w := httptest.NewRecorder()
+
 	LogJSONBodyMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})).ServeHTTP(w, r)
@@ -126,3 +168,27 @@ func TestMultipartIsIgnored(t *testing.T) {
 	ass.Equal(http.StatusOK, w.Code)
 	ass.Len(buf.Bytes(), 0)
 }
+
+func TestSafeModeRestoresBody(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+
+	body := `{"email":"user@example.com","password":"secret"}`
+	r := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/vnd.api+json")
+
+	w := httptest.NewRecorder()
+
+	LogJSONBodyMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, err := io.ReadAll(r.Body)
+		ass.NoError(err)
+		ass.Equal(body, string(read))
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, r)
+
+	ass.Equal(http.StatusOK, w.Code)
+	ass.Contains(buf.String(), "body_sha256")
+	ass.NotContains(buf.String(), "secret")
+}
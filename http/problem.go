@@ -0,0 +1,193 @@
+package http
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"sync/atomic"
+)
+
+// ProblemContentType is the media type of RFC 9457 Problem Details.
+const ProblemContentType = "application/problem+json"
+
+// problemDetails switches every error response to Problem Details, see UseProblemDetails.
+var problemDetails atomic.Bool
+
+// UseProblemDetails writes every error response (status >= 400) built with BadRequest, NotFound,
+// InternalError... as an RFC 9457 Problem Details document instead of {"message", "details"}.
+// It is off by default so that existing clients keep the current shape, call it once at startup.
+// Response.AsProblem opts a single response in.
+func UseProblemDetails(enabled bool) {
+	problemDetails.Store(enabled)
+}
+
+// Problem is an RFC 9457 Problem Details document.
+//
+// Example usage:
+//
+//	return ProblemResponse(&Problem{
+//		Type:   "https://example.com/problems/out-of-credit",
+//		Title:  "You do not have enough credit.",
+//		Status: http.StatusForbidden,
+//		Detail: "Your current balance is 30, but that costs 50.",
+//	}).WithExtension("balance", 30)
+type Problem struct {
+	// Type is a URI identifying the problem type (default: "about:blank")
+	Type string
+	// Title is a short summary of the problem type (default: the status text)
+	Title string
+	// Status is the HTTP status code
+	Status int
+	// Detail explains this occurrence of the problem
+	Detail string
+	// Instance is a URI identifying this occurrence (default: the request path)
+	Instance string
+	// Errors lists the invalid fields of a request
+	Errors FieldErrors
+	// Extensions are additional members, they cannot override the members above
+	Extensions map[string]any
+}
+
+// MarshalJSON flattens the extension members next to the standard ones.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+6)
+	maps.Copy(members, p.Extensions)
+
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	members["title"] = p.Title
+	if p.Title == "" {
+		members["title"] = http.StatusText(p.Status)
+	}
+	members["status"] = p.Status
+	delete(members, "detail")
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	delete(members, "instance")
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	delete(members, "errors")
+	if len(p.Errors) > 0 {
+		members["errors"] = p.Errors
+	}
+	return json.Marshal(members)
+}
+
+// ProblemResponse returns a response always written as Problem Details, whatever UseProblemDetails.
+func ProblemResponse(p *Problem) *Response {
+	return &Response{Payload: p, StatusCode: p.Status, problem: true}
+}
+
+// AsProblem writes this error response as Problem Details, whatever UseProblemDetails.
+func (r *Response) AsProblem() *Response {
+	r.problem = true
+	return r
+}
+
+// WithProblemType sets the "type" member of the Problem Details document (default: "about:blank").
+func (r *Response) WithProblemType(uri string) *Response {
+	r.problemType = uri
+	return r
+}
+
+// WithInstance sets the "instance" member of the Problem Details document (default: the request path).
+func (r *Response) WithInstance(uri string) *Response {
+	r.instance = uri
+	return r
+}
+
+// WithExtension adds a member to the Problem Details document.
+// ⚠️ Extensions are only written in Problem Details mode.
+func (r *Response) WithExtension(key string, value any) *Response {
+	if r.extensions == nil {
+		r.extensions = make(map[string]any)
+	}
+	r.extensions[key] = value
+	return r
+}
+
+// problemOf returns the Problem Details document of an error response,
+// or nil when the response is written in the {"message", "details"} shape.
+func problemOf(resp *Response) *Problem {
+	if p, ok := resp.Payload.(*Problem); ok {
+		problem := *p
+		problem.Status = resp.StatusCode
+		problem.Extensions = mergeExtensions(p.Extensions, resp.extensions)
+		if problem.Type == "" {
+			problem.Type = resp.problemType
+		}
+		if problem.Instance == "" {
+			problem.Instance = resp.instance
+		}
+		return &problem
+	}
+
+	if resp.StatusCode < http.StatusBadRequest || !(resp.problem || problemDetails.Load()) {
+		return nil
+	}
+
+	problem := &Problem{
+		Type:       resp.problemType,
+		Status:     resp.StatusCode,
+		Instance:   resp.instance,
+		Extensions: mergeExtensions(nil, resp.extensions),
+	}
+
+	switch payload := resp.Payload.(type) {
+	case nil:
+	case map[string]string:
+		for k, v := range payload {
+			problem.setMember(k, v)
+		}
+	case map[string]any:
+		for k, v := range payload {
+			problem.setMember(k, v)
+		}
+	default:
+		// A custom payload is kept in the legacy shape
+		return nil
+	}
+	return problem
+}
+
+// setMember maps a member of the {"message", "details"} payload.
+func (p *Problem) setMember(key string, value any) {
+	switch key {
+	case "message":
+		// The title is the status text for "about:blank"
+	case "details":
+		p.Detail, _ = value.(string)
+	case "errors":
+		p.Errors, _ = value.(FieldErrors)
+	default:
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]any)
+		}
+		p.Extensions[key] = value
+	}
+}
+
+func mergeExtensions(base, extra map[string]any) map[string]any {
+	if len(base) == 0 && len(extra) == 0 {
+		return nil
+	}
+	merged := make(map[string]any, len(base)+len(extra))
+	maps.Copy(merged, base)
+	maps.Copy(merged, extra)
+	return merged
+}
+
+// withRequestInstance defaults the "instance" member to the request path, on a copy
+// since responses can be shared between requests (package-level variables, ErrorRegistry).
+func withRequestInstance(resp *Response, r *http.Request) *Response {
+	if resp == nil || resp.instance != "" {
+		return resp
+	}
+	copied := *resp
+	copied.instance = r.URL.Path
+	return &copied
+}
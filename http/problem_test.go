@@ -0,0 +1,115 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorsKeepTheLegacyShapeByDefault(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, NotFound("user 42"))
+
+	ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	ass.JSONEq(`{"code":"not_found","message":"not found","details":"user 42"}`, rec.Body.String())
+}
+
+func TestAsProblemOptsASingleResponseIn(t *testing.T) {
+	ass := assert.New(t)
+
+	h := JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return NotFound("user 42").AsProblem().
+			WithProblemType("https://example.com/problems/user-not-found").
+			WithExtension("user_id", 42)
+	})
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	ass.Equal(http.StatusNotFound, rec.Code)
+	ass.Equal(ProblemContentType, rec.Header().Get("Content-Type"))
+	ass.JSONEq(`{
+		"type": "https://example.com/problems/user-not-found",
+		"title": "Not Found",
+		"status": 404,
+		"detail": "user 42",
+		"instance": "/users/42",
+		"code": "not_found",
+		"user_id": 42
+	}`, rec.Body.String())
+}
+
+func TestUseProblemDetailsSwitchesEveryError(t *testing.T) {
+	ass := assert.New(t)
+	UseProblemDetails(true)
+	t.Cleanup(func() { UseProblemDetails(false) })
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, fieldErrorsResponse(http.StatusUnprocessableEntity, FieldErrors{{Field: "email", Rule: "required", Message: "is required"}}))
+
+	ass.Equal(ProblemContentType, rec.Header().Get("Content-Type"))
+	ass.JSONEq(`{
+		"type": "about:blank",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "1 invalid field",
+		"code": "validation_failed",
+		"errors": [{"field": "email", "rule": "required", "message": "is required"}]
+	}`, rec.Body.String())
+
+	// Success responses and custom payloads are untouched
+	rec = httptest.NewRecorder()
+	respondWithJSON(rec, OK(map[string]string{"message": "hello"}))
+	ass.JSONEq(`{"message":"hello"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	respondWithJSON(rec, &Response{Payload: []string{"custom"}, StatusCode: http.StatusBadRequest})
+	ass.JSONEq(`["custom"]`, rec.Body.String())
+}
+
+func TestProblemResponse(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, ProblemResponse(&Problem{
+		Type:       "https://example.com/problems/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Extensions: map[string]any{"balance": 30, "status": "ignored"},
+	}))
+
+	ass.Equal(http.StatusForbidden, rec.Code)
+	ass.Equal(ProblemContentType, rec.Header().Get("Content-Type"))
+	ass.JSONEq(`{
+		"type": "https://example.com/problems/out-of-credit",
+		"title": "You do not have enough credit.",
+		"status": 403,
+		"detail": "Your current balance is 30, but that costs 50.",
+		"balance": 30
+	}`, rec.Body.String())
+}
+
+func TestSharedResponsesGetTheInstanceOfEachRequest(t *testing.T) {
+	ass := assert.New(t)
+
+	shared := NotFound("no such user").AsProblem()
+	handler := JSON(func(w http.ResponseWriter, r *http.Request) *Response { return shared })
+
+	var wg sync.WaitGroup
+	for _, path := range []string{"/users/1", "/users/2", "/users/3"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			ass.Contains(rec.Body.String(), `"instance":"`+path+`"`)
+		}()
+	}
+	wg.Wait()
+	ass.Empty(shared.instance)
+}
@@ -0,0 +1,323 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRanges is the number of ranges above which a Range header is ignored,
+// many small ranges cost more to serve than the whole content.
+const maxRanges = 32
+
+// WithLastModified sets the Last-Modified header, used by If-Modified-Since, If-Unmodified-Since
+// and If-Range to answer conditional requests.
+func (r *Response) WithLastModified(t time.Time) *Response {
+	return r.AddHeader("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// AsAttachment sets Content-Disposition so that browsers download the payload as filename.
+func (r *Response) AsAttachment(filename string) *Response {
+	return r.AddHeader("Content-Disposition", contentDisposition("attachment", filename))
+}
+
+// AsInline sets Content-Disposition so that browsers display the payload, saved as filename.
+func (r *Response) AsInline(filename string) *Response {
+	return r.AddHeader("Content-Disposition", contentDisposition("inline", filename))
+}
+
+// contentDisposition encodes non-ASCII file names as RFC 2231 filename* parameters.
+func contentDisposition(disposition, filename string) string {
+	if filename == "" {
+		return disposition
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+}
+
+// byteRange is a resolved range of a content, from start to start+length.
+type byteRange struct {
+	start, length int64
+}
+
+func (br byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, size)
+}
+
+// errUnsatisfiableRange means that no range overlaps the content.
+var errUnsatisfiableRange = errors.New("unsatisfiable range")
+
+// parseRange resolves a Range header against a content of size bytes.
+// ok is false when the header is invalid and must be ignored (RFC 9110 §14.2).
+func parseRange(header string, size int64) (ranges []byteRange, ok bool, err error) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return nil, false, nil
+	}
+
+	var total int64
+	parts := strings.Split(spec, ",")
+	if len(parts) > maxRanges {
+		return nil, false, nil
+	}
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, found := strings.Cut(part, "-")
+		if !found {
+			return nil, false, nil
+		}
+
+		var br byteRange
+		if first == "" {
+			// Suffix range: the last N bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, false, nil
+			}
+			if n == 0 || size == 0 {
+				// Nothing to send: an empty content has no last bytes
+				continue
+			}
+			br = byteRange{start: max(size-n, 0), length: min(n, size)}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, false, nil
+			}
+			end := size - 1
+			if last != "" {
+				if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+					return nil, false, nil
+				}
+			}
+			if start >= size {
+				continue
+			}
+			br = byteRange{start: start, length: min(end, size-1) - start + 1}
+		}
+		total += br.length
+		ranges = append(ranges, br)
+	}
+
+	if len(ranges) == 0 {
+		return nil, true, errUnsatisfiableRange
+	}
+	if total > size {
+		// Overlapping ranges, the whole content is cheaper
+		return nil, false, nil
+	}
+	return ranges, true, nil
+}
+
+// ifRangeMatches evaluates If-Range: the range applies only when the validator is current.
+// Dates are compared exactly, entity tags strongly (RFC 9110 §13.1.5).
+func ifRangeMatches(ifRange string, header http.Header) bool {
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		etag := header.Get("ETag")
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+	lastModified := header.Get("Last-Modified")
+	return lastModified != "" && ifRange == lastModified
+}
+
+// preconditionFailed evaluates If-Match, or If-Unmodified-Since in its absence (RFC 9110 §13.2.2).
+func preconditionFailed(r *http.Request, header http.Header) bool {
+	if im := r.Header.Get("If-Match"); im != "" {
+		etag := header.Get("ETag")
+		if strings.TrimSpace(im) == "*" {
+			return etag == ""
+		}
+		if etag == "" || strings.HasPrefix(etag, "W/") {
+			return true
+		}
+		for _, candidate := range strings.Split(im, ",") {
+			if strings.TrimSpace(candidate) == etag {
+				return false
+			}
+		}
+		return true
+	}
+
+	ius := r.Header.Get("If-Unmodified-Since")
+	lastModified := header.Get("Last-Modified")
+	if ius == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ius)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return modified.Truncate(time.Second).After(since)
+}
+
+// writeSeekable writes an io.ReadSeeker payload returned to Stream with a 200 status.
+//
+// Behavior:
+//   - Sets Accept-Ranges: bytes and Content-Length, sniffs the Content-Type when not set.
+//   - Answers 412 to a failed If-Match / If-Unmodified-Since and 304 to a matching
+//     If-None-Match / If-Modified-Since, against the ETag and Last-Modified headers of the response.
+//   - Answers a Range request of GET with 206: a single range is written as is with Content-Range,
+//     several ranges as multipart/byteranges. Unsatisfiable ranges answer 416.
+//   - Ignores Range when If-Range does not match the current ETag or Last-Modified,
+//     when it is invalid, or when it has more than 32 or overlapping ranges.
+//
+// Example usage:
+//
+//	mux.HandleFunc("GET /exports/{id}", Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+//		f, err := os.Open(exportPath(r.PathValue("id")))
+//		if err != nil {
+//			return NotFound("export not found")
+//		}
+//		info, err := f.Stat()
+//		if err != nil {
+//			_ = f.Close()
+//			return InternalError("export unreadable")
+//		}
+//		return OK(f).OnClose(f.Close).SetContentType("text/csv").WithLastModified(info.ModTime()).AsAttachment("export.csv")
+//	}))
+func writeSeekable(w http.ResponseWriter, r *http.Request, resp *Response, content io.ReadSeeker) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "seek error", err.Error())
+		return
+	}
+
+	// The validators are checked before the headers are written, error responses do not carry them
+	header := make(http.Header, len(resp.header))
+	for k, v := range resp.header {
+		header.Set(k, v)
+	}
+	contentType := resp.contentType
+	if contentType == "" {
+		var sniff [512]byte
+		n, _ := io.ReadFull(content, sniff[:])
+		contentType = http.DetectContentType(sniff[:n])
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "seek error", err.Error())
+			return
+		}
+	}
+
+	if preconditionFailed(r, header) {
+		respondWithJSON(w, PreconditionFailed("the resource has changed"))
+		return
+	}
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, header) {
+		copyHeader(w.Header(), header)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var ranges []byteRange
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && r.Method == http.MethodGet &&
+		ifRangeMatches(r.Header.Get("If-Range"), header) {
+		var ok bool
+		ranges, ok, err = parseRange(rangeHeader, size)
+		if errors.Is(err, errUnsatisfiableRange) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			respondWithJSON(w, errorWithStatus(http.StatusRequestedRangeNotSatisfiable, "no range overlaps the content"))
+			return
+		}
+		if !ok {
+			ranges = nil
+		}
+	}
+
+	copyHeader(w.Header(), header)
+	header = w.Header()
+	header.Set("Accept-Ranges", "bytes")
+
+	switch len(ranges) {
+	case 0:
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			_, _ = io.Copy(w, content)
+		}
+
+	case 1:
+		br := ranges[0]
+		if _, err := content.Seek(br.start, io.SeekStart); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "seek error", err.Error())
+			return
+		}
+		header.Set("Content-Type", contentType)
+		header.Set("Content-Range", br.contentRange(size))
+		header.Set("Content-Length", strconv.FormatInt(br.length, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = io.CopyN(w, content, br.length)
+
+	default:
+		mw := multipart.NewWriter(io.Discard)
+		header.Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		header.Set("Content-Length", strconv.FormatInt(multipartLength(mw.Boundary(), ranges, contentType, size), 10))
+		w.WriteHeader(http.StatusPartialContent)
+		_ = writeRanges(w, mw.Boundary(), ranges, contentType, size, content)
+	}
+}
+
+// writeRanges writes the multipart/byteranges body, or only its framing when content is nil.
+func writeRanges(w io.Writer, boundary string, ranges []byteRange, contentType string, size int64, content io.ReadSeeker) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, br := range ranges {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {contentType},
+			"Content-Range": {br.contentRange(size)},
+		})
+		if err != nil {
+			return err
+		}
+		if content == nil {
+			continue
+		}
+		if _, err := content.Seek(br.start, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(part, content, br.length); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+// multipartLength computes the Content-Length of a multipart/byteranges body without reading the content.
+func multipartLength(boundary string, ranges []byteRange, contentType string, size int64) int64 {
+	counter := &countingWriter{}
+	_ = writeRanges(counter, boundary, ranges, contentType, size, nil)
+	for _, br := range ranges {
+		counter.n += br.length
+	}
+	return counter.n
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	cw.n += int64(len(b))
+	return len(b), nil
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rangeContent = "0123456789abcdefghij"
+
+var rangeModified = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func serveRange(t *testing.T, method string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(strings.NewReader(rangeContent)).
+			SetContentType("text/plain").
+			AddHeader("ETag", `"v1"`).
+			WithLastModified(rangeModified).
+			AsAttachment("data.txt")
+	})
+	req := httptest.NewRequest(method, "/file", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestStreamSeekerFullContent(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := serveRange(t, http.MethodGet, nil)
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("bytes", rec.Header().Get("Accept-Ranges"))
+	ass.Equal("20", rec.Header().Get("Content-Length"))
+	ass.Equal("Sat, 01 Mar 2025 12:00:00 GMT", rec.Header().Get("Last-Modified"))
+	ass.Equal(`attachment; filename=data.txt`, rec.Header().Get("Content-Disposition"))
+	ass.Equal(rangeContent, rec.Body.String())
+
+	rec = serveRange(t, http.MethodHead, nil)
+	ass.Equal("20", rec.Header().Get("Content-Length"))
+	ass.Empty(rec.Body.String())
+}
+
+func TestStreamSeekerSingleRange(t *testing.T) {
+	ass := assert.New(t)
+
+	for header, want := range map[string]struct{ contentRange, body string }{
+		"bytes=2-5":   {"bytes 2-5/20", "2345"},
+		"bytes=15-":   {"bytes 15-19/20", "fghij"},
+		"bytes=-3":    {"bytes 17-19/20", "hij"},
+		"bytes=18-99": {"bytes 18-19/20", "ij"},
+	} {
+		rec := serveRange(t, http.MethodGet, map[string]string{"Range": header})
+
+		ass.Equal(http.StatusPartialContent, rec.Code, header)
+		ass.Equal(want.contentRange, rec.Header().Get("Content-Range"), header)
+		ass.Equal(want.body, rec.Body.String(), header)
+		ass.Equal("text/plain", rec.Header().Get("Content-Type"), header)
+	}
+}
+
+func TestStreamSeekerMultipleRanges(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := serveRange(t, http.MethodGet, map[string]string{"Range": "bytes=0-1, 10-12"})
+
+	ass.Equal(http.StatusPartialContent, rec.Code)
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	require.NoError(t, err)
+	ass.Equal("multipart/byteranges", mediaType)
+	ass.Equal(strconv.Itoa(rec.Body.Len()), rec.Header().Get("Content-Length"))
+
+	mr := multipart.NewReader(rec.Body, params["boundary"])
+	for _, want := range []struct{ contentRange, body string }{{"bytes 0-1/20", "01"}, {"bytes 10-12/20", "abc"}} {
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		ass.Equal(want.contentRange, part.Header.Get("Content-Range"))
+		ass.Equal("text/plain", part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		ass.Equal(want.body, string(body))
+	}
+	_, err = mr.NextPart()
+	ass.ErrorIs(err, io.EOF)
+}
+
+func TestStreamSeekerIgnoredRanges(t *testing.T) {
+	ass := assert.New(t)
+
+	for _, headers := range []map[string]string{
+		{"Range": "items=0-1"},
+		{"Range": "bytes=5-2"},
+		{"Range": "bytes=0-15, 5-19"},
+		{"Range": "bytes=0-1", "If-Range": `"v0"`},
+		{"Range": "bytes=0-1", "If-Range": "Sat, 01 Mar 2025 11:00:00 GMT"},
+	} {
+		rec := serveRange(t, http.MethodGet, headers)
+
+		ass.Equal(http.StatusOK, rec.Code, headers)
+		ass.Equal(rangeContent, rec.Body.String(), headers)
+	}
+
+	for _, ifRange := range []string{`"v1"`, "Sat, 01 Mar 2025 12:00:00 GMT"} {
+		rec := serveRange(t, http.MethodGet, map[string]string{"Range": "bytes=0-1", "If-Range": ifRange})
+		ass.Equal(http.StatusPartialContent, rec.Code, ifRange)
+	}
+}
+
+func TestStreamSeekerUnsatisfiableRange(t *testing.T) {
+	rec := serveRange(t, http.MethodGet, map[string]string{"Range": "bytes=20-30"})
+
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+	assert.Equal(t, "bytes */20", rec.Header().Get("Content-Range"))
+	assert.Contains(t, rec.Body.String(), CodeRangeNotSatisfiable)
+}
+
+func TestStreamSeekerEmptyContent(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(bytes.NewReader(nil)).SetContentType("text/plain")
+	})
+	for _, rangeHeader := range []string{"bytes=-5", "bytes=0-", "bytes=0-0,-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/file", nil)
+		req.Header.Set("Range", rangeHeader)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		ass.Equal(http.StatusRequestedRangeNotSatisfiable, rec.Code, rangeHeader)
+		ass.Equal("bytes */0", rec.Header().Get("Content-Range"), rangeHeader)
+	}
+}
+
+func TestStreamSeekerConditionalRequests(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := serveRange(t, http.MethodGet, map[string]string{"If-None-Match": `"v1"`})
+	ass.Equal(http.StatusNotModified, rec.Code)
+	ass.Empty(rec.Body.String())
+	ass.Equal(`"v1"`, rec.Header().Get("ETag"))
+
+	rec = serveRange(t, http.MethodGet, map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 12:00:00 GMT"})
+	ass.Equal(http.StatusNotModified, rec.Code)
+
+	rec = serveRange(t, http.MethodGet, map[string]string{"If-Match": `"v0"`})
+	ass.Equal(http.StatusPreconditionFailed, rec.Code)
+	ass.Empty(rec.Header().Get("Content-Disposition"))
+
+	rec = serveRange(t, http.MethodGet, map[string]string{"If-Unmodified-Since": "Sat, 01 Mar 2025 11:00:00 GMT"})
+	ass.Equal(http.StatusPreconditionFailed, rec.Code)
+
+	rec = serveRange(t, http.MethodGet, map[string]string{"If-Match": `"v0", "v1"`})
+	ass.Equal(http.StatusOK, rec.Code)
+}
+
+func TestContentDisposition(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Equal("attachment", contentDisposition("attachment", ""))
+	ass.Equal(`inline; filename="my report.pdf"`, contentDisposition("inline", "my report.pdf"))
+	ass.Equal(`attachment; filename*=utf-8''r%C3%A9sum%C3%A9.pdf`, contentDisposition("attachment", "résumé.pdf"))
+}
+
+func TestStreamSniffsSeekerContentType(t *testing.T) {
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(strings.NewReader("<html><body>hi</body></html>"))
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<html><body>hi</body></html>", rec.Body.String())
+}
@@ -0,0 +1,248 @@
+package http
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitResult is the outcome of a single RateLimitAlgorithm.Take call.
+type RateLimitResult struct {
+	Allowed bool
+	// Limit is the maximum number of requests of the quota
+	Limit int
+	// Remaining is the number of requests still allowed right now
+	Remaining int
+	// Reset is the time until the quota is fully available again
+	Reset time.Duration
+	// RetryAfter is the time to wait before a new attempt when the request is denied
+	RetryAfter time.Duration
+}
+
+// RateLimitAlgorithm computes a rate limit decision from the state stored for a key.
+// A nil state means the key has not been seen yet (or has expired).
+type RateLimitAlgorithm interface {
+	// Take consumes one request and returns the new state to store
+	Take(state []byte, now time.Time) ([]byte, RateLimitResult)
+	// TTL is how long an untouched state must be kept
+	TTL() time.Duration
+}
+
+// TokenBucket allows bursts of up to Limit requests, refilled at Limit requests per Period.
+type TokenBucket struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Take implements RateLimitAlgorithm.
+// The state holds the remaining tokens and the last refill time.
+func (tb TokenBucket) Take(state []byte, now time.Time) ([]byte, RateLimitResult) {
+	capacity := float64(tb.Limit)
+	perNano := capacity / float64(tb.Period)
+
+	tokens, last := capacity, now
+	if len(state) == 16 {
+		tokens = math.Float64frombits(binary.BigEndian.Uint64(state[:8]))
+		last = time.Unix(0, int64(binary.BigEndian.Uint64(state[8:])))
+	}
+
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+float64(elapsed)*perNano)
+	}
+
+	res := RateLimitResult{Limit: tb.Limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - tokens) / perNano))
+	}
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration(math.Ceil((capacity - tokens) / perNano))
+
+	newState := make([]byte, 16)
+	binary.BigEndian.PutUint64(newState[:8], math.Float64bits(tokens))
+	binary.BigEndian.PutUint64(newState[8:], uint64(now.UnixNano()))
+	return newState, res
+}
+
+// Validate reports a zero or negative Limit or Period.
+func (tb TokenBucket) Validate() error {
+	if tb.Limit <= 0 || tb.Period <= 0 {
+		return errors.New("TokenBucket Limit and Period must be positive")
+	}
+	return nil
+}
+
+// TTL implements RateLimitAlgorithm: an untouched bucket is full after one Period.
+func (tb TokenBucket) TTL() time.Duration {
+	return tb.Period
+}
+
+// SlidingWindow allows Limit requests per Window.
+// The count of the previous window is weighted by its overlap with the sliding window,
+// which smooths the bursts allowed by fixed windows with a constant memory usage.
+type SlidingWindow struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Take implements RateLimitAlgorithm.
+// The state holds the current window start, the previous and the current window counts.
+func (sw SlidingWindow) Take(state []byte, now time.Time) ([]byte, RateLimitResult) {
+	window := int64(sw.Window)
+	start := now.UnixNano() / window * window
+
+	var prev, curr int64
+	if len(state) == 24 {
+		storedStart := int64(binary.BigEndian.Uint64(state[:8]))
+		switch storedStart {
+		case start:
+			prev = int64(binary.BigEndian.Uint64(state[8:16]))
+			curr = int64(binary.BigEndian.Uint64(state[16:]))
+		case start - window:
+			prev = int64(binary.BigEndian.Uint64(state[16:]))
+		}
+	}
+
+	elapsed := now.UnixNano() - start
+	weight := 1 - float64(elapsed)/float64(window)
+	limit := float64(sw.Limit)
+
+	res := RateLimitResult{Limit: sw.Limit, Reset: time.Duration(window - elapsed)}
+	estimated := float64(prev)*weight + float64(curr)
+	if estimated+1 <= limit {
+		curr++
+		estimated++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = sw.retryAfter(prev, curr, elapsed)
+	}
+	res.Remaining = int(math.Max(0, math.Floor(limit-estimated)))
+
+	newState := make([]byte, 24)
+	binary.BigEndian.PutUint64(newState[:8], uint64(start))
+	binary.BigEndian.PutUint64(newState[8:16], uint64(prev))
+	binary.BigEndian.PutUint64(newState[16:], uint64(curr))
+	return newState, res
+}
+
+// retryAfter estimates when the previous window weight will have decreased enough
+// to allow one more request, or falls back to the end of the current window.
+func (sw SlidingWindow) retryAfter(prev, curr, elapsed int64) time.Duration {
+	window := int64(sw.Window)
+	if prev > 0 && curr < int64(sw.Limit) {
+		// prev * (1 - t/window) + curr + 1 <= limit
+		t := float64(window) * (1 - float64(int64(sw.Limit)-1-curr)/float64(prev))
+		if wait := int64(math.Ceil(t)) - elapsed; wait > 0 && wait < window-elapsed {
+			return time.Duration(wait)
+		}
+	}
+	return time.Duration(window - elapsed)
+}
+
+// Validate reports a zero or negative Limit or Window.
+func (sw SlidingWindow) Validate() error {
+	if sw.Limit <= 0 || sw.Window <= 0 {
+		return errors.New("SlidingWindow Limit and Window must be positive")
+	}
+	return nil
+}
+
+// TTL implements RateLimitAlgorithm: the previous window count matters during two windows.
+func (sw SlidingWindow) TTL() time.Duration {
+	return 2 * sw.Window
+}
+
+// RateLimitConfig configures RateLimitMiddleware.
+type RateLimitConfig struct {
+	// Algorithm computes the decision (required), e.g. TokenBucket{Limit: 100, Period: time.Minute}
+	Algorithm RateLimitAlgorithm
+	// Store keeps the state per key (default: NewMemoryRateLimitStore())
+	Store RateLimitStore
+	// Key identifies the client (default: KeyByIP()), an empty key disables limiting for the request
+	Key KeyFunc
+}
+
+// RateLimitMiddleware returns an HTTP middleware limiting the request rate per key.
+//
+// Behavior:
+//   - Extracts the key of the request with RateLimitConfig.Key.
+//   - Sets RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset (seconds) on every response.
+//   - Rejects the request with 429, a Retry-After header and the TooManyRequests payload
+//     when the quota is exhausted.
+//   - Lets the request through (fail open) and logs a warning if the store fails.
+//
+// ⚠️ A missing Algorithm, or one whose Validate method fails, panics when the middleware is built,
+// it is a programming error.
+//
+// Example usage:
+//
+//	limit := RateLimitMiddleware(logger, RateLimitConfig{
+//		Algorithm: TokenBucket{Limit: 100, Period: time.Minute},
+//		Key:       CombineKeys(KeyByIP(), KeyByRoute()),
+//	})
+//	http.Handle("/api", limit(myHandler))
+func RateLimitMiddleware(logger *slog.Logger, cfg RateLimitConfig) func(http.Handler) http.Handler {
+	if cfg.Algorithm == nil {
+		panic("http: RateLimitConfig.Algorithm is required")
+	}
+	if v, ok := cfg.Algorithm.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			panic("http: invalid RateLimitConfig.Algorithm: " + err.Error())
+		}
+	}
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	key := cfg.Key
+	if key == nil {
+		key = KeyByIP()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var res RateLimitResult
+			err := store.Update(r.Context(), k, cfg.Algorithm.TTL(), func(state []byte) []byte {
+				newState, result := cfg.Algorithm.Take(state, time.Now())
+				res = result
+				return newState
+			})
+			if err != nil {
+				loggerWithRequestID(logger, r).Warn("rate limit store error",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Any("error", err),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+				respondWithJSON(w, TooManyRequests("rate limit exceeded"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// KeyFunc extracts the rate limit key of a request. An empty key means "do not limit".
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP keys requests by client IP.
+// X-Forwarded-For is only trusted when the direct peer belongs to trustedProxies:
+// the header is then read from right to left and the first address outside
+// trustedProxies is used, so that a client cannot spoof its IP.
+//
+// Example usage:
+//
+//	KeyByIP(netip.MustParsePrefix("10.0.0.0/8"))
+func KeyByIP(trustedProxies ...netip.Prefix) KeyFunc {
+	return func(r *http.Request) string {
+		ip := ClientIP(r, trustedProxies...)
+		if !ip.IsValid() {
+			return ""
+		}
+		return "ip:" + ip.String()
+	}
+}
+
+// ClientIP returns the client IP of r, see KeyByIP for the trusted proxies handling.
+// The returned address is invalid if RemoteAddr cannot be parsed.
+func ClientIP(r *http.Request, trustedProxies ...netip.Prefix) netip.Addr {
+	peer := parseIP(r.RemoteAddr)
+	if !peer.IsValid() || !isTrusted(peer, trustedProxies) {
+		return peer
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := parseIP(strings.TrimSpace(forwarded[i]))
+		if !ip.IsValid() {
+			// A malformed hop cannot be trusted any further
+			return peer
+		}
+		if !isTrusted(ip, trustedProxies) {
+			return ip
+		}
+		peer = ip
+	}
+	return peer
+}
+
+// KeyByAPIKey keys requests by the value of the header carrying the API key (e.g. "X-API-Key").
+// The value is used as is, only use it after the key has been authenticated.
+func KeyByAPIKey(header string) KeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(header); v != "" {
+			return "apikey:" + v
+		}
+		return ""
+	}
+}
+
+// KeyByJWTSubject keys requests by the "sub" claim of the bearer token.
+// ⚠️ The token signature is NOT verified here: register this middleware after
+// the authentication middleware, otherwise clients can pick their own key.
+func KeyByJWTSubject() KeyFunc {
+	return func(r *http.Request) string {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return ""
+		}
+		parts := strings.Split(strings.TrimSpace(token), ".")
+		if len(parts) != 3 {
+			return ""
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			return ""
+		}
+		var claims struct {
+			Subject string `json:"sub"`
+		}
+		if err := json.Unmarshal(raw, &claims); err != nil || claims.Subject == "" {
+			return ""
+		}
+		return "sub:" + claims.Subject
+	}
+}
+
+// KeyByRoute keys requests by the ServeMux pattern that matched, or the path if none.
+// Combine it with another KeyFunc to get a per-client and per-route quota.
+func KeyByRoute() KeyFunc {
+	return func(r *http.Request) string {
+		if r.Pattern != "" {
+			return "route:" + r.Pattern
+		}
+		return "route:" + r.Method + " " + r.URL.Path
+	}
+}
+
+// CombineKeys joins the keys of several KeyFunc. The request is not limited if any key is empty.
+func CombineKeys(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			k := key(r)
+			if k == "" {
+				return ""
+			}
+			parts = append(parts, k)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+func parseIP(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap()
+}
+
+func isTrusted(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, p := range trustedProxies {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
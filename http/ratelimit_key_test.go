@@ -0,0 +1,66 @@
+package http
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyByIPIgnoresUntrustedForwardedFor(t *testing.T) {
+	ass := assert.New(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.7:5555"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	ass.Equal("ip:203.0.113.7", KeyByIP()(r))
+}
+
+func TestKeyByIPWithTrustedProxies(t *testing.T) {
+	ass := assert.New(t)
+
+	trusted := netip.MustParsePrefix("10.0.0.0/8")
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.2:5555"
+	// The left-most value is spoofed by the client, 10.0.0.1 is another proxy
+	r.Header.Set("X-Forwarded-For", "1.2.3.4, 198.51.100.1, 10.0.0.1")
+
+	ass.Equal("ip:198.51.100.1", KeyByIP(trusted)(r))
+
+	r.Header.Set("X-Forwarded-For", "not-an-ip")
+	ass.Equal("ip:10.0.0.2", KeyByIP(trusted)(r))
+}
+
+func TestKeyByJWTSubject(t *testing.T) {
+	ass := assert.New(t)
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user-1"}`))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer header."+payload+".signature")
+
+	ass.Equal("sub:user-1", KeyByJWTSubject()(r))
+
+	r.Header.Set("Authorization", "Bearer garbage")
+	ass.Empty(KeyByJWTSubject()(r))
+}
+
+func TestCombineKeys(t *testing.T) {
+	ass := assert.New(t)
+
+	var key string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		key = CombineKeys(KeyByAPIKey("X-API-Key"), KeyByRoute())(r)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	r.Header.Set("X-API-Key", "k1")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+
+	ass.Equal("apikey:k1|route:GET /users/{id}", key)
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// RateLimitStore keeps the rate limit state of each key.
+type RateLimitStore interface {
+	// Update atomically replaces the state of key with the result of fn.
+	// fn receives nil when the key is unknown or expired, the new state expires after ttl.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) []byte) error
+}
+
+// memorySweepInterval is how often expired keys are removed from a MemoryRateLimitStore.
+const memorySweepInterval = time.Minute
+
+// MemoryRateLimitStore is an in-process RateLimitStore, suited for a single instance.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryRateLimitEntry
+	lastSweep time.Time
+}
+
+type memoryRateLimitEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		entries:   make(map[string]memoryRateLimitEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Update implements RateLimitStore.
+func (s *MemoryRateLimitStore) Update(_ context.Context, key string, ttl time.Duration, fn func(state []byte) []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		s.sweep(now)
+	}
+
+	var state []byte
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		state = entry.state
+	}
+	s.entries[key] = memoryRateLimitEntry{state: fn(state), expires: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for k, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+	s.lastSweep = now
+}
+
+const (
+	badgerRateLimitPrefix = "ratelimit:"
+	badgerMaxRetries      = 5
+)
+
+// BadgerRateLimitStore is a RateLimitStore persisted in Badger,
+// so that quotas survive restarts. States expire with the Badger TTL.
+type BadgerRateLimitStore struct {
+	db *badger.DB
+}
+
+// NewBadgerRateLimitStore creates a store on an opened database (see database.LoadBadger).
+func NewBadgerRateLimitStore(db *badger.DB) *BadgerRateLimitStore {
+	return &BadgerRateLimitStore{db: db}
+}
+
+// Update implements RateLimitStore. Conflicting transactions are retried.
+func (s *BadgerRateLimitStore) Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) []byte) error {
+	k := []byte(badgerRateLimitPrefix + key)
+
+	var err error
+	for range badgerMaxRetries {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = s.db.Update(func(txn *badger.Txn) error {
+			var state []byte
+			item, err := txn.Get(k)
+			switch {
+			case err == nil:
+				if state, err = item.ValueCopy(nil); err != nil {
+					return err
+				}
+			case !errors.Is(err, badger.ErrKeyNotFound):
+				return err
+			}
+			return txn.SetEntry(badger.NewEntry(k, fn(state)).WithTTL(ttl))
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testRateLimitStore(t *testing.T, store RateLimitStore) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	var seen []byte
+	req.NoError(store.Update(ctx, "k", time.Minute, func(state []byte) []byte {
+		seen = state
+		return []byte("v1")
+	}))
+	req.Nil(seen)
+
+	req.NoError(store.Update(ctx, "k", time.Minute, func(state []byte) []byte {
+		seen = state
+		return []byte("v2")
+	}))
+	req.Equal([]byte("v1"), seen)
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	testRateLimitStore(t, NewMemoryRateLimitStore())
+}
+
+func TestMemoryRateLimitStoreExpiry(t *testing.T) {
+	req := require.New(t)
+	store := NewMemoryRateLimitStore()
+
+	req.NoError(store.Update(context.Background(), "k", -time.Second, func(state []byte) []byte {
+		return []byte("expired")
+	}))
+
+	var seen []byte
+	req.NoError(store.Update(context.Background(), "k", time.Minute, func(state []byte) []byte {
+		seen = state
+		return nil
+	}))
+	req.Nil(seen)
+}
+
+func TestBadgerRateLimitStore(t *testing.T) {
+	testRateLimitStore(t, NewBadgerRateLimitStore(openTestBadger(t)))
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	ass := assert.New(t)
+
+	tb := TokenBucket{Limit: 2, Period: 2 * time.Second}
+	now := time.Unix(1000, 0)
+
+	state, res := tb.Take(nil, now)
+	ass.True(res.Allowed)
+	ass.Equal(1, res.Remaining)
+
+	state, res = tb.Take(state, now)
+	ass.True(res.Allowed)
+	ass.Equal(0, res.Remaining)
+	ass.Equal(2*time.Second, res.Reset)
+
+	state, res = tb.Take(state, now)
+	ass.False(res.Allowed)
+	ass.Equal(time.Second, res.RetryAfter)
+
+	// One token is refilled every second
+	_, res = tb.Take(state, now.Add(time.Second))
+	ass.True(res.Allowed)
+	ass.Equal(0, res.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ass := assert.New(t)
+
+	sw := SlidingWindow{Limit: 2, Window: 10 * time.Second}
+	now := time.Unix(1000, 0)
+
+	state, res := sw.Take(nil, now)
+	ass.True(res.Allowed)
+	state, res = sw.Take(state, now.Add(time.Second))
+	ass.True(res.Allowed)
+	ass.Equal(0, res.Remaining)
+
+	state, res = sw.Take(state, now.Add(2*time.Second))
+	ass.False(res.Allowed)
+	ass.Equal(8*time.Second, res.RetryAfter)
+
+	// At the middle of the next window, the previous one still counts for 1 request
+	state, res = sw.Take(state, now.Add(15*time.Second))
+	ass.True(res.Allowed)
+	ass.Equal(0, res.Remaining)
+
+	_, res = sw.Take(state, now.Add(16*time.Second))
+	ass.False(res.Allowed)
+
+	// Two windows later everything is forgotten
+	_, res = sw.Take(state, now.Add(30*time.Second))
+	ass.True(res.Allowed)
+	ass.Equal(1, res.Remaining)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := RateLimitMiddleware(nil, RateLimitConfig{
+		Algorithm: TokenBucket{Limit: 1, Period: time.Minute},
+	})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK("ok")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("1", rec.Header().Get("RateLimit-Limit"))
+	ass.Equal("0", rec.Header().Get("RateLimit-Remaining"))
+	ass.Equal("60", rec.Header().Get("RateLimit-Reset"))
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusTooManyRequests, rec.Code)
+	ass.Equal("60", rec.Header().Get("Retry-After"))
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("too many requests", body["message"])
+	ass.Equal("rate limit exceeded", body["details"])
+
+	// Another client has its own quota
+	other := httptest.NewRequest(http.MethodGet, "/", nil)
+	other.RemoteAddr = "192.0.2.99:1234"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, other)
+	ass.Equal(http.StatusOK, rec.Code)
+}
+
+func TestRateLimitMiddlewareWithoutKey(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := RateLimitMiddleware(nil, RateLimitConfig{
+		Algorithm: TokenBucket{Limit: 1, Period: time.Minute},
+		Key:       KeyByAPIKey("X-API-Key"),
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		ass.Equal(http.StatusOK, rec.Code)
+		ass.Empty(rec.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitMiddlewareRejectsInvalidConfig(t *testing.T) {
+	ass := assert.New(t)
+
+	for _, cfg := range []RateLimitConfig{
+		{},
+		{Algorithm: TokenBucket{Limit: 10}},
+		{Algorithm: TokenBucket{Period: time.Minute}},
+		{Algorithm: SlidingWindow{Limit: 10, Window: -time.Second}},
+	} {
+		ass.Panics(func() { RateLimitMiddleware(nil, cfg) }, "%+v", cfg)
+	}
+	ass.NotPanics(func() {
+		RateLimitMiddleware(nil, RateLimitConfig{Algorithm: TokenBucket{Limit: 10, Period: time.Minute}})
+	})
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoveryConfig configures RecoveryMiddleware.
+type RecoveryConfig struct {
+	// OnPanic is called after logging, e.g. to report the panic to an error tracker
+	OnPanic func(r *http.Request, recovered any, stack []byte)
+}
+
+// RecoveryMiddleware returns an HTTP middleware that recovers from panics in the next handler.
+//
+// Behavior:
+//   - Logs the panic value and the stack trace at ERROR level.
+//   - Calls RecoveryConfig.OnPanic if set.
+//   - Writes the InternalError payload ({"message","details"}) if nothing has been written yet,
+//     the panic value itself is never sent to the client.
+//   - Otherwise re-panics with http.ErrAbortHandler once logged, so that net/http aborts the
+//     connection and the client sees a truncated response rather than one that looks complete.
+//   - Always re-panics http.ErrAbortHandler, without logging: it is how handlers (e.g. streamed
+//     responses) abort on purpose.
+//
+// Example usage:
+//
+//	http.Handle("/api", RecoveryMiddleware(logger, RecoveryConfig{})(JSON(myHandler)))
+func RecoveryMiddleware(logger *slog.Logger, cfg RecoveryConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := newResponseRecorder(w)
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				loggerWithRequestID(logger, r).Error("panic recovered",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("panic", fmt.Sprint(recovered)),
+					slog.String("stack", string(stack)),
+				)
+
+				if cfg.OnPanic != nil {
+					cfg.OnPanic(r, recovered, stack)
+				}
+
+				// Too late for a structured response, the partial body must not pass for a complete one
+				if rw.wroteHeader {
+					panic(http.ErrAbortHandler)
+				}
+				respondWithJSON(rw, InternalError("unexpected error"))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
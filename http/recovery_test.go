@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryReturnsInternalError(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+
+	var reported any
+	handler := RecoveryMiddleware(logger, RecoveryConfig{
+		OnPanic: func(r *http.Request, recovered any, stack []byte) {
+			reported = recovered
+		},
+	})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusInternalServerError, rec.Code)
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("internal server error", body["message"])
+	ass.NotContains(rec.Body.String(), "boom")
+
+	ass.Equal("boom", reported)
+	ass.Contains(buf.String(), "panic recovered")
+	ass.Contains(buf.String(), "boom")
+	ass.Contains(buf.String(), "stack")
+}
+
+func TestRecoveryAbortsAlreadyWrittenResponse(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+
+	handler := RecoveryMiddleware(logger, RecoveryConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("partial"))
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	ass.PanicsWithValue(http.ErrAbortHandler, func() {
+		handler.ServeHTTP(rec, req)
+	})
+	ass.Equal(http.StatusAccepted, rec.Code)
+	ass.Equal("partial", rec.Body.String())
+	ass.Contains(buf.String(), "panic recovered")
+}
+
+func TestRecoveryRepanicsOnAbort(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+
+	handler := RecoveryMiddleware(logger, RecoveryConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	ass.PanicsWithValue(http.ErrAbortHandler, func() {
+		handler.ServeHTTP(rec, req)
+	})
+	ass.Len(buf.Bytes(), 0)
+}
@@ -0,0 +1,264 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIDHeader is the header read and written by RequestIDMiddleware when none is configured.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming IDs so that a client cannot flood logs.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+type traceContextKey struct{}
+
+// TraceContext holds the W3C Trace Context headers of a request.
+type TraceContext struct {
+	// TraceParent is "version-traceid-parentid-flags"
+	TraceParent string
+	TraceState  string
+}
+
+// TraceID returns the trace-id field of TraceParent.
+func (tc TraceContext) TraceID() string {
+	if len(tc.TraceParent) < 35 {
+		return ""
+	}
+	return tc.TraceParent[3:35]
+}
+
+// childTraceParent returns TraceParent with a new random parent-id, for an outgoing call.
+// An invalid TraceParent is returned as is.
+func (tc TraceContext) childTraceParent() string {
+	if !isValidTraceParent(tc.TraceParent) {
+		return tc.TraceParent
+	}
+	var spanID [8]byte
+	_, _ = rand.Read(spanID[:])
+	return tc.TraceParent[:36] + hex.EncodeToString(spanID[:]) + tc.TraceParent[52:]
+}
+
+// RequestIDConfig configures RequestIDMiddleware.
+// The zero value reads and writes X-Request-ID and generates ULIDs.
+type RequestIDConfig struct {
+	// Header carrying the request ID (default: X-Request-ID)
+	Header string
+	// Generator creates an ID when the incoming request has none (default: NewULID)
+	Generator func() string
+}
+
+// RequestIDMiddleware returns an HTTP middleware that propagates a request ID.
+//
+// Behavior:
+//   - Reuses the incoming ID from the configured header if it is valid
+//     (printable ASCII, at most 128 characters), otherwise generates a new one.
+//   - Stores the ID in the request context (see RequestIDFromContext).
+//   - Echoes the ID on the response header.
+//   - Stores a valid incoming W3C "traceparent" (and "tracestate") in the context
+//     (see TraceContextFromContext).
+//   - LogJSONBodyMiddleware adds them as "request_id" and "trace_id" to its log lines when
+//     registered after this middleware.
+//
+// Example usage:
+//
+//	http.Handle("/api", RequestIDMiddleware(RequestIDConfig{})(LogJSONBodyMiddleware(logger)(myHandler)))
+func RequestIDMiddleware(cfg RequestIDConfig) func(http.Handler) http.Handler {
+	header := cfg.Header
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	generate := cfg.Generator
+	if generate == nil {
+		generate = NewULID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !isValidRequestID(id) {
+				id = generate()
+			}
+
+			ctx := WithRequestID(r.Context(), id)
+			if tp := r.Header.Get("traceparent"); isValidTraceParent(tp) {
+				ctx = WithTraceContext(ctx, TraceContext{TraceParent: tp, TraceState: r.Header.Get("tracestate")})
+			}
+
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithTraceContext returns a copy of ctx carrying the trace context.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context stored in ctx, if any.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// RequestIDTransport is an http.RoundTripper forwarding the request ID and the
+// trace context found in the outgoing request context, so IDs survive service hops.
+// The traceparent keeps the trace-id and flags with a new parent-id, so that downstream
+// spans attach to this service rather than to its caller.
+//
+// Example usage:
+//
+//	client := &http.Client{Transport: &RequestIDTransport{}}
+//	req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+//	client.Do(req)
+type RequestIDTransport struct {
+	// Base is the underlying transport (default: http.DefaultTransport)
+	Base http.RoundTripper
+	// Header carrying the request ID (default: X-Request-ID)
+	Header string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RequestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	header := t.Header
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+
+	id := RequestIDFromContext(r.Context())
+	tc, hasTrace := TraceContextFromContext(r.Context())
+	setID := id != "" && r.Header.Get(header) == ""
+	setTrace := hasTrace && r.Header.Get("traceparent") == ""
+	if !setID && !setTrace {
+		return base.RoundTrip(r)
+	}
+
+	// A RoundTripper must not modify the original request
+	clone := r.Clone(r.Context())
+	if setID {
+		clone.Header.Set(header, id)
+	}
+	if setTrace {
+		clone.Header.Set("traceparent", tc.childTraceParent())
+		if tc.TraceState != "" {
+			clone.Header.Set("tracestate", tc.TraceState)
+		}
+	}
+	return base.RoundTrip(clone)
+}
+
+// loggerWithRequestID enriches the logger with the request and trace IDs of r, if any.
+func loggerWithRequestID(logger *slog.Logger, r *http.Request) *slog.Logger {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		logger = logger.With(slog.String("request_id", id))
+	}
+	if tc, ok := TraceContextFromContext(r.Context()); ok {
+		logger = logger.With(slog.String("trace_id", tc.TraceID()))
+	}
+	return logger
+}
+
+// isValidTraceParent checks the version 00 format: 2-32-16-2 lowercase hex digits.
+func isValidTraceParent(tp string) bool {
+	if len(tp) != 55 || tp[2] != '-' || tp[35] != '-' || tp[52] != '-' {
+		return false
+	}
+	for i := 0; i < len(tp); i++ {
+		c := tp[i]
+		if i == 2 || i == 35 || i == 52 {
+			continue
+		}
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID: 48 bits of millisecond timestamp followed by
+// 80 random bits, encoded as 26 Crockford base32 characters (lexicographically sortable).
+func NewULID() string {
+	var b [16]byte
+	putTimestamp(b[:6])
+	_, _ = rand.Read(b[6:])
+
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	// 128 bits are encoded in 26 groups of 5 bits, the first one holding only 3 bits
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// NewUUIDv7 generates an RFC 9562 version 7 UUID (time-ordered).
+func NewUUIDv7() string {
+	var b [16]byte
+	putTimestamp(b[:6])
+	_, _ = rand.Read(b[6:])
+
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+// putTimestamp writes the current Unix time in milliseconds as a 48-bit big-endian integer.
+func putTimestamp(b []byte) {
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
@@ -0,0 +1,178 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	ass := assert.New(t)
+
+	var seen string
+	handler := RequestIDMiddleware(RequestIDConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Len(seen, 26)
+	ass.Equal(seen, rec.Header().Get(DefaultRequestIDHeader))
+}
+
+func TestRequestIDIsReused(t *testing.T) {
+	ass := assert.New(t)
+
+	var seen string
+	handler := RequestIDMiddleware(RequestIDConfig{Header: "X-Correlation-ID"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Correlation-ID", "abc-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal("abc-123", seen)
+	ass.Equal("abc-123", rec.Header().Get("X-Correlation-ID"))
+}
+
+func TestInvalidRequestIDIsReplaced(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := RequestIDMiddleware(RequestIDConfig{Generator: NewUUIDv7})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultRequestIDHeader, "bad id\nwith newline")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Regexp(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), rec.Header().Get(DefaultRequestIDHeader))
+}
+
+func TestULIDIsSortable(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Regexp(regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`), NewULID())
+	ass.NotEqual(NewULID(), NewULID())
+}
+
+func TestRequestIDIsLogged(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelDebug)
+
+	handler := RequestIDMiddleware(RequestIDConfig{})(LogJSONBodyMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"john"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DefaultRequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var logEntry map[string]any
+	ass.NoError(json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &logEntry))
+	ass.Equal("req-42", logEntry["request_id"])
+}
+
+func TestRequestIDTransportForwardsID(t *testing.T) {
+	ass := assert.New(t)
+
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(DefaultRequestIDHeader)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(WithRequestID(t.Context(), "req-42"), http.MethodGet, server.URL, nil)
+	ass.NoError(err)
+
+	client := &http.Client{Transport: &RequestIDTransport{}}
+	resp, err := client.Do(req)
+	ass.NoError(err)
+	defer resp.Body.Close()
+
+	ass.Equal("req-42", received)
+	// The original request is left untouched
+	ass.Empty(req.Header.Get(DefaultRequestIDHeader))
+}
+
+func TestRequestIDTransportStartsNewSpan(t *testing.T) {
+	ass := assert.New(t)
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("traceparent"))
+	}))
+	defer server.Close()
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := WithTraceContext(t.Context(), TraceContext{TraceParent: traceParent})
+	client := &http.Client{Transport: &RequestIDTransport{}}
+	for range 2 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		ass.NoError(err)
+		resp, err := client.Do(req)
+		ass.NoError(err)
+		_ = resp.Body.Close()
+	}
+
+	ass.Len(received, 2)
+	for _, tp := range received {
+		ass.True(isValidTraceParent(tp), tp)
+		// Same trace and flags, parent-id of this service
+		ass.Equal(traceParent[:36], tp[:36])
+		ass.Equal(traceParent[52:], tp[52:])
+		ass.NotEqual(traceParent[36:52], tp[36:52])
+	}
+	ass.NotEqual(received[0], received[1], "one span per outgoing call")
+}
+
+func TestTraceContextIsStored(t *testing.T) {
+	ass := assert.New(t)
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	var seen TraceContext
+	var ok bool
+	handler := RequestIDMiddleware(RequestIDConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, ok = TraceContextFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", traceParent)
+	req.Header.Set("tracestate", "vendor=1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ass.True(ok)
+	ass.Equal(traceParent, seen.TraceParent)
+	ass.Equal("vendor=1", seen.TraceState)
+	ass.Equal("4bf92f3577b34da6a3ce929d0e0e4736", seen.TraceID())
+}
+
+func TestInvalidTraceParentIsIgnored(t *testing.T) {
+	ass := assert.New(t)
+
+	var ok bool
+	handler := RequestIDMiddleware(RequestIDConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok = TraceContextFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-XYZ-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ass.False(ok)
+}
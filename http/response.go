@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Response struct {
@@ -13,6 +17,14 @@ type Response struct {
 	StatusCode  int
 	contentType string
 	header      map[string]string
+
+	// Problem Details (RFC 9457), see problem.go
+	problem     bool
+	problemType string
+	instance    string
+	extensions  map[string]any
+
+	onClose []func() error
 }
 
 func (r *Response) Error() string {
@@ -32,6 +44,106 @@ func (r *Response) AddHeader(key, value string) *Response {
 	return r
 }
 
+// OnClose registers fn to release the payload once the response is written, e.g. the *os.File
+// returned to Stream, which is read after the handler returns. It runs even when the write fails.
+func (r *Response) OnClose(fn func() error) *Response {
+	r.onClose = append(r.onClose, fn)
+	return r
+}
+
+// release runs the OnClose functions, last registered first.
+func (r *Response) release() {
+	if r == nil {
+		return
+	}
+	for i := len(r.onClose) - 1; i >= 0; i-- {
+		_ = r.onClose[i]()
+	}
+}
+
+// Stable error codes of the "code" member of error payloads, clients can branch on them
+// rather than parsing "message" or "details".
+const (
+	CodeBadRequest           = "bad_request"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeNotAcceptable        = "not_acceptable"
+	CodeConflict             = "conflict"
+	CodeGone                 = "gone"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRangeNotSatisfiable  = "range_not_satisfiable"
+	CodeUnprocessableEntity  = "unprocessable_entity"
+	CodeValidationFailed     = "validation_failed"
+	CodeTooManyRequests      = "too_many_requests"
+	CodeInternalError        = "internal_error"
+	CodeBadGateway           = "bad_gateway"
+	CodeServiceUnavailable   = "service_unavailable"
+	CodeGatewayTimeout       = "gateway_timeout"
+)
+
+// errorCodes are the default codes of error statuses.
+var errorCodes = map[int]string{
+	http.StatusBadRequest:                   CodeBadRequest,
+	http.StatusUnauthorized:                 CodeUnauthorized,
+	http.StatusForbidden:                    CodeForbidden,
+	http.StatusNotFound:                     CodeNotFound,
+	http.StatusMethodNotAllowed:             CodeMethodNotAllowed,
+	http.StatusNotAcceptable:                CodeNotAcceptable,
+	http.StatusConflict:                     CodeConflict,
+	http.StatusGone:                         CodeGone,
+	http.StatusPreconditionFailed:           CodePreconditionFailed,
+	http.StatusRequestEntityTooLarge:        CodeRequestTooLarge,
+	http.StatusUnsupportedMediaType:         CodeUnsupportedMediaType,
+	http.StatusRequestedRangeNotSatisfiable: CodeRangeNotSatisfiable,
+	http.StatusUnprocessableEntity:          CodeUnprocessableEntity,
+	http.StatusTooManyRequests:              CodeTooManyRequests,
+	http.StatusInternalServerError:          CodeInternalError,
+	http.StatusBadGateway:                   CodeBadGateway,
+	http.StatusServiceUnavailable:           CodeServiceUnavailable,
+	http.StatusGatewayTimeout:               CodeGatewayTimeout,
+}
+
+// errorCode returns the default code of an error status, derived from the status text when unknown.
+func errorCode(statusCode int) string {
+	if code, ok := errorCodes[statusCode]; ok {
+		return code
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(statusCode)), " ", "_")
+}
+
+// errorWithStatus builds an error response in the {"code", "message", "details"} shape for any status.
+func errorWithStatus(statusCode int, details string) *Response {
+	return &Response{
+		Payload: map[string]string{
+			"code":    errorCode(statusCode),
+			"message": strings.ToLower(http.StatusText(statusCode)),
+			"details": details,
+		},
+		StatusCode: statusCode,
+	}
+}
+
+// WithCode replaces the "code" member of an error payload with an application specific code,
+// e.g. "insufficient_funds".
+func (r *Response) WithCode(code string) *Response {
+	switch payload := r.Payload.(type) {
+	case map[string]string:
+		payload["code"] = code
+	case map[string]any:
+		payload["code"] = code
+	}
+	return r
+}
+
+// WithRetryAfter sets the Retry-After header (in seconds, rounded up), e.g. on 429 and 503 responses.
+func (r *Response) WithRetryAfter(d time.Duration) *Response {
+	return r.AddHeader("Retry-After", strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10))
+}
+
 func OK(content interface{}) *Response {
 	return &Response{Payload: content, StatusCode: http.StatusOK}
 }
@@ -40,57 +152,146 @@ func Created(content interface{}) *Response {
 	return &Response{Payload: content, StatusCode: http.StatusCreated}
 }
 
+// Accepted acknowledges a request processed asynchronously, content usually points to its status.
+func Accepted(content interface{}) *Response {
+	return &Response{Payload: content, StatusCode: http.StatusAccepted}
+}
+
+// PartialContent answers a range request, contentRange is e.g. "items 0-24/100".
+func PartialContent(content interface{}, contentRange string) *Response {
+	return (&Response{Payload: content, StatusCode: http.StatusPartialContent}).AddHeader("Content-Range", contentRange)
+}
+
+func NoContent() *Response {
+	return &Response{StatusCode: http.StatusNoContent}
+}
+
+// MovedPermanently redirects to location (301), clients may change POST to GET.
+func MovedPermanently(location string) *Response {
+	return redirect(http.StatusMovedPermanently, location)
+}
+
+// Found redirects to location (302), clients may change POST to GET.
+func Found(location string) *Response {
+	return redirect(http.StatusFound, location)
+}
+
+// TemporaryRedirect redirects to location (307), keeping the method and the body.
+func TemporaryRedirect(location string) *Response {
+	return redirect(http.StatusTemporaryRedirect, location)
+}
+
+// PermanentRedirect redirects to location (308), keeping the method and the body.
+func PermanentRedirect(location string) *Response {
+	return redirect(http.StatusPermanentRedirect, location)
+}
+
+func redirect(statusCode int, location string) *Response {
+	return (&Response{StatusCode: statusCode}).AddHeader("Location", location)
+}
+
 func BadRequest(details string) *Response {
-	return &Response{
-		Payload:    map[string]string{"message": "bad request", "details": details},
-		StatusCode: http.StatusBadRequest,
-	}
+	return errorWithStatus(http.StatusBadRequest, details)
 }
 
-func NotFound(details string) *Response {
-	return &Response{
-		Payload:    map[string]string{"message": "not found", "details": details},
-		StatusCode: http.StatusNotFound,
-	}
+func Unauthorized(details string) *Response {
+	return errorWithStatus(http.StatusUnauthorized, details)
 }
 
 func Forbidden(details string) *Response {
-	return &Response{
-		Payload:    map[string]string{"message": "forbidden", "details": details},
-		StatusCode: http.StatusForbidden,
-	}
+	return errorWithStatus(http.StatusForbidden, details)
 }
 
-func Unauthorized(details string) *Response {
-	return &Response{
-		Payload:    map[string]string{"message": "unauthorized", "details": details},
-		StatusCode: http.StatusUnauthorized,
-	}
+func NotFound(details string) *Response {
+	return errorWithStatus(http.StatusNotFound, details)
+}
+
+// MethodNotAllowed lists the allowed methods in the Allow header, as required by RFC 9110.
+func MethodNotAllowed(details string, allowed ...string) *Response {
+	return errorWithStatus(http.StatusMethodNotAllowed, details).AddHeader("Allow", strings.Join(allowed, ", "))
+}
+
+// NotAcceptable tells that no representation matches the Accept header.
+func NotAcceptable(details string) *Response {
+	return errorWithStatus(http.StatusNotAcceptable, details)
+}
+
+func Conflict(details string) *Response {
+	return errorWithStatus(http.StatusConflict, details)
+}
+
+// Gone tells that the resource was deleted and will not come back.
+func Gone(details string) *Response {
+	return errorWithStatus(http.StatusGone, details)
+}
+
+// PreconditionFailed answers a failed If-Match or If-Unmodified-Since precondition.
+func PreconditionFailed(details string) *Response {
+	return errorWithStatus(http.StatusPreconditionFailed, details)
+}
+
+func RequestEntityTooLarge(details string) *Response {
+	return errorWithStatus(http.StatusRequestEntityTooLarge, details)
+}
+
+func UnsupportedMediaType(details string) *Response {
+	return errorWithStatus(http.StatusUnsupportedMediaType, details)
+}
+
+func UnprocessableEntity(details string) *Response {
+	return errorWithStatus(http.StatusUnprocessableEntity, details)
+}
+
+// TooManyRequests tells the client to slow down, use WithRetryAfter to tell it when to retry.
+func TooManyRequests(details string) *Response {
+	return errorWithStatus(http.StatusTooManyRequests, details)
 }
 
 func InternalError(details string) *Response {
-	return &Response{
-		Payload:    map[string]string{"message": "internal server error", "details": details},
-		StatusCode: http.StatusInternalServerError,
-	}
+	return errorWithStatus(http.StatusInternalServerError, details)
 }
 
-func NoContent() *Response {
-	return &Response{StatusCode: http.StatusNoContent}
+// BadGateway tells that an upstream service answered with an invalid response.
+func BadGateway(details string) *Response {
+	return errorWithStatus(http.StatusBadGateway, details)
+}
+
+func ServiceUnavailable(details string) *Response {
+	return errorWithStatus(http.StatusServiceUnavailable, details)
+}
+
+func GatewayTimeout(details string) *Response {
+	return errorWithStatus(http.StatusGatewayTimeout, details)
 }
 
 type Handler func(w http.ResponseWriter, r *http.Request) *Response
 
 func JSON(h Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		respondWithJSON(w, h(w, r))
+		respond(w, r, h(w, r))
 	}
 }
 
+// Stream adapts a Handler whose payload is copied rather than encoded:
+// an io.Reader is copied as is, an io.ReadSeeker answered
+// with 200 also supports Range and conditional requests (see writeSeekable), an EventStream
+// (or a <-chan Event, iter.Seq[Event]) is written as Server-Sent Events, other payloads are
+// written like JSON does.
+// ⚠️ The payload is never closed: release it with Response.OnClose, e.g. OK(f).OnClose(f.Close).
 func Stream(h Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		resp := h(w, r)
+		resp := withRequestInstance(h(w, r), r)
+		defer resp.release()
+
+		if stream, ok := eventStreamOf(resp.Payload); ok {
+			writeEvents(w, r, resp, stream)
+			return
+		}
 
+		if rs, ok := resp.Payload.(io.ReadSeeker); ok && resp.StatusCode == http.StatusOK {
+			writeSeekable(w, r, resp, rs)
+			return
+		}
 		if rc, ok := resp.Payload.(io.Reader); ok {
 			if resp.contentType != "" {
 				w.Header().Set("Content-Type", resp.contentType)
@@ -107,24 +308,33 @@ func Stream(h Handler) http.HandlerFunc {
 			return
 		}
 
-		respondWithJSON(w, resp)
+		writeResponse(w, r, resp)
 	}
 }
 
 func respondWithJSON(w http.ResponseWriter, resp *Response) {
-	// 204 → no body allowed
-	if resp.StatusCode == http.StatusNoContent {
-		w.WriteHeader(http.StatusNoContent)
+	// 204 and 304 → no body allowed, redirects have none unless a payload is given
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified ||
+		(resp.Payload == nil && resp.StatusCode >= 300 && resp.StatusCode < 400) {
+		for k, v := range resp.header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(resp.StatusCode)
 		return
 	}
 
+	payload, contentType := resp.Payload, "application/json; charset=utf-8"
+	if problem := problemOf(resp); problem != nil {
+		payload, contentType = problem, ProblemContentType
+	}
+
 	buf := bytes.Buffer{}
-	if err := json.NewEncoder(&buf).Encode(resp.Payload); err != nil {
+	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
 		http.Error(w, "encoding error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Type", contentType)
 
 	for k, v := range resp.header {
 		w.Header().Set(k, v)
@@ -139,6 +349,7 @@ func respondWithError(w http.ResponseWriter, code int, msg string, details strin
 
 	respondWithJSON(w, &Response{
 		Payload: map[string]string{
+			"code":    errorCode(code),
 			"message": msg,
 			"details": details,
 		},
@@ -6,9 +6,13 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJSONOK(t *testing.T) {
@@ -69,6 +73,54 @@ func TestStreamSuccess(t *testing.T) {
 	ass.Equal(data, rec.Body.String())
 }
 
+func TestStreamClosesFileWithOnClose(t *testing.T) {
+	ass := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "export.csv")
+	require.NoError(t, os.WriteFile(path, []byte("id,name\n1,one\n"), 0o600))
+
+	var file *os.File
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		f, err := os.Open(path)
+		if err != nil {
+			return NotFound("export not found")
+		}
+		file = f
+		return OK(f).OnClose(f.Close).SetContentType("text/csv")
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/exports/1", nil))
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal("id,name\n1,one\n", rec.Body.String())
+	// Released once written
+	ass.ErrorIs(file.Close(), os.ErrClosed)
+}
+
+func TestOnCloseRunsOncePerResponse(t *testing.T) {
+	ass := assert.New(t)
+
+	var order []string
+	release := func(name string) func() error {
+		return func() error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	h := func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK("payload").OnClose(release("first")).OnClose(release("second"))
+	}
+
+	JSON(h)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Equal([]string{"second", "first"}, order)
+
+	// Payloads written like JSON does are released once too
+	order = nil
+	Stream(h)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Equal([]string{"second", "first"}, order)
+}
+
 func TestStreamFallbackToJSON(t *testing.T) {
 	ass := assert.New(t)
 
@@ -163,3 +215,72 @@ func TestEncodingFailureReturns500(t *testing.T) {
 	ass.Equal(http.StatusInternalServerError, rec.Code)
 	ass.Contains(rec.Body.String(), "encoding error")
 }
+
+func TestErrorPayloadsHaveAStableCode(t *testing.T) {
+	ass := assert.New(t)
+
+	cases := map[string]*Response{
+		CodeBadRequest:         BadRequest("x"),
+		CodeGone:               Gone("x"),
+		CodePreconditionFailed: PreconditionFailed("x"),
+		CodeRequestTooLarge:    RequestEntityTooLarge("x"),
+		CodeBadGateway:         BadGateway("x"),
+		"insufficient_funds":   Conflict("x").WithCode("insufficient_funds"),
+	}
+	for code, resp := range cases {
+		rec := httptest.NewRecorder()
+		respondWithJSON(rec, resp)
+
+		var body map[string]string
+		ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+		ass.Equal(code, body["code"])
+		ass.Equal("x", body["details"])
+	}
+}
+
+func TestMethodNotAllowedSetsAllow(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, MethodNotAllowed("use GET", http.MethodGet, http.MethodHead))
+
+	ass.Equal(http.StatusMethodNotAllowed, rec.Code)
+	ass.Equal("GET, HEAD", rec.Header().Get("Allow"))
+}
+
+func TestTooManyRequestsWithRetryAfter(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, TooManyRequests("slow down").WithRetryAfter(1500*time.Millisecond))
+
+	ass.Equal(http.StatusTooManyRequests, rec.Code)
+	ass.Equal("2", rec.Header().Get("Retry-After"))
+}
+
+func TestRedirectsHaveNoBody(t *testing.T) {
+	ass := assert.New(t)
+
+	for _, resp := range []*Response{MovedPermanently("/a"), Found("/a"), TemporaryRedirect("/a"), PermanentRedirect("/a")} {
+		rec := httptest.NewRecorder()
+		respondWithJSON(rec, resp)
+
+		ass.Equal(resp.StatusCode, rec.Code)
+		ass.Equal("/a", rec.Header().Get("Location"))
+		ass.Empty(rec.Body.String())
+	}
+}
+
+func TestSuccessConstructors(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := httptest.NewRecorder()
+	respondWithJSON(rec, Accepted(map[string]string{"status": "/jobs/1"}))
+	ass.Equal(http.StatusAccepted, rec.Code)
+
+	rec = httptest.NewRecorder()
+	respondWithJSON(rec, PartialContent([]int{1, 2}, "items 0-1/10"))
+	ass.Equal(http.StatusPartialContent, rec.Code)
+	ass.Equal("items 0-1/10", rec.Header().Get("Content-Range"))
+	ass.JSONEq(`[1,2]`, rec.Body.String())
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSecurityHeaders(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := SecurityHeadersMiddleware(DefaultSecurityHeaders())(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		ass.Empty(CSPNonceFromContext(r.Context()))
+		return OK("ok")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ass.Equal("nosniff", rec.Header().Get("X-Content-Type-Options"))
+	ass.Equal("max-age=31536000; includeSubDomains", rec.Header().Get("Strict-Transport-Security"))
+	ass.Equal("default-src 'none'; frame-ancestors 'none'", rec.Header().Get("Content-Security-Policy"))
+	ass.Equal("DENY", rec.Header().Get("X-Frame-Options"))
+	ass.Equal("no-referrer", rec.Header().Get("Referrer-Policy"))
+	ass.Equal("same-origin", rec.Header().Get("Cross-Origin-Opener-Policy"))
+	ass.Equal("require-corp", rec.Header().Get("Cross-Origin-Embedder-Policy"))
+	ass.NotEmpty(rec.Header().Get("Permissions-Policy"))
+}
+
+func TestSecurityHeadersNonce(t *testing.T) {
+	ass := assert.New(t)
+
+	cfg := DefaultSecurityHeaders()
+	cfg.ContentSecurityPolicy = "script-src 'nonce-{nonce}'"
+
+	var nonce string
+	handler := SecurityHeadersMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce = CSPNonceFromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.Len(nonce, 24)
+	ass.Equal("script-src 'nonce-"+nonce+"'", rec.Header().Get("Content-Security-Policy"))
+
+	first := nonce
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ass.NotEqual(first, nonce)
+}
+
+func TestSecurityHeadersRouteOverride(t *testing.T) {
+	ass := assert.New(t)
+
+	route := SecurityHeadersConfig{FrameOptions: "SAMEORIGIN", CrossOriginEmbedderPolicy: "unsafe-none"}
+	chain := NewChain(
+		SecurityHeadersMiddleware(DefaultSecurityHeaders()),
+		SecurityHeadersMiddleware(route),
+	)
+
+	rec := httptest.NewRecorder()
+	chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	ass.Equal("SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+	ass.Equal("unsafe-none", rec.Header().Get("Cross-Origin-Embedder-Policy"))
+	// Headers left empty by the route keep the server-wide value
+	ass.Equal("no-referrer", rec.Header().Get("Referrer-Policy"))
+}
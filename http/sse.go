@@ -0,0 +1,205 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventStreamContentType is the media type of Server-Sent Events.
+const EventStreamContentType = "text/event-stream"
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is sent back by the client in the Last-Event-ID header when it reconnects
+	ID string
+	// Event is the event type, listened to with addEventListener (default: "message")
+	Event string
+	// Data is written as is for strings and []byte, as JSON otherwise
+	Data any
+	// Retry tells the client how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// EventStream is a Stream payload written as Server-Sent Events.
+// A <-chan Event or iter.Seq[Event] payload is written as an EventStream with the defaults.
+type EventStream struct {
+	// Events are sent until the channel is closed, set either Events or Seq
+	Events <-chan Event
+	// Seq is iterated until it ends, set either Events or Seq
+	Seq iter.Seq[Event]
+	// Heartbeat is the idle time after which a comment is sent to keep the connection
+	// open through proxies (default: 15s, negative disables)
+	Heartbeat time.Duration
+	// Retry is sent before the first event, it tells clients how long to wait before reconnecting
+	Retry time.Duration
+}
+
+const defaultHeartbeat = 15 * time.Second
+
+// LastEventID returns the ID of the last event received by a reconnecting client,
+// from the Last-Event-ID header or the "lastEventId" query parameter used by polyfills.
+// Handlers resume the stream after it, or from the start when it is empty.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("lastEventId")
+}
+
+// eventStreamOf returns the EventStream of an SSE payload.
+func eventStreamOf(payload any) (*EventStream, bool) {
+	switch p := payload.(type) {
+	case *EventStream:
+		return p, p != nil
+	case EventStream:
+		return &p, true
+	case <-chan Event:
+		return &EventStream{Events: p}, true
+	case chan Event:
+		return &EventStream{Events: p}, true
+	case iter.Seq[Event]:
+		return &EventStream{Seq: p}, true
+	default:
+		return nil, false
+	}
+}
+
+// writeEvents writes an EventStream returned to Stream.
+//
+// Behavior:
+//   - Sets Content-Type: text/event-stream, Cache-Control: no-cache and X-Accel-Buffering: no
+//     (disables nginx buffering), writes the status at once and flushes every event.
+//   - Multi-line data (CRLF, CR or LF line breaks) is split into several "data:" lines.
+//   - Sends a ": heartbeat" comment after Heartbeat without events.
+//   - Stops when the events end or when the request context is done (client disconnected),
+//     an iter.Seq[Event] then stops at its next yield.
+//
+// ⚠️ A data encoding error aborts the response with http.ErrAbortHandler, see respondStream.
+// Return NoContent() instead of an EventStream to tell a client to stop reconnecting.
+//
+// Example usage:
+//
+//	mux.HandleFunc("GET /notifications", Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+//		return OK(&EventStream{Events: notifier.Subscribe(r.Context(), LastEventID(r))})
+//	}))
+func writeEvents(w http.ResponseWriter, r *http.Request, resp *Response, stream *EventStream) {
+	ctx := r.Context()
+	events := stream.Events
+	if stream.Seq != nil {
+		ch := make(chan Event)
+		events = ch
+		go func() {
+			defer close(ch)
+			for event := range stream.Seq {
+				select {
+				case ch <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	heartbeat := stream.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultHeartbeat
+	}
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if heartbeat > 0 {
+		timer = time.NewTimer(heartbeat)
+		defer timer.Stop()
+		idle = timer.C
+	}
+
+	w.Header().Set("Content-Type", EventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	for k, v := range resp.header {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	bw := bufio.NewWriter(w)
+	flush := func() {
+		_ = bw.Flush()
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		if timer != nil {
+			timer.Reset(heartbeat)
+		}
+	}
+
+	if stream.Retry > 0 {
+		writeRetry(bw, stream.Retry)
+		_, _ = bw.WriteString("\n")
+	}
+	flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-idle:
+			_, _ = bw.WriteString(": heartbeat\n\n")
+			flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(bw, event); err != nil {
+				panic(http.ErrAbortHandler)
+			}
+			flush()
+		}
+	}
+}
+
+func writeEvent(bw *bufio.Writer, event Event) error {
+	var data string
+	switch d := event.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+
+	// Line breaks would end the field early
+	if event.ID != "" {
+		_, _ = bw.WriteString("id: " + singleLine(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		_, _ = bw.WriteString("event: " + singleLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		writeRetry(bw, event.Retry)
+	}
+	// CRLF, CR and LF all end a line for the client
+	data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+	for _, line := range strings.Split(data, "\n") {
+		_, _ = bw.WriteString("data: " + line + "\n")
+	}
+	_, err := bw.WriteString("\n")
+	return err
+}
+
+func writeRetry(bw *bufio.Writer, retry time.Duration) {
+	_, _ = bw.WriteString("retry: " + strconv.FormatInt(retry.Milliseconds(), 10) + "\n")
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"iter"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamWritesEvents(t *testing.T) {
+	ass := assert.New(t)
+	ch := make(chan Event, 3)
+	ch <- Event{ID: "1", Event: "greeting", Data: "hello\nworld"}
+	ch <- Event{ID: "2", Data: map[string]int{"count": 2}, Retry: 2 * time.Second}
+	ch <- Event{Data: []byte("raw")}
+	close(ch)
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(&EventStream{Events: ch, Retry: time.Second})
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.Equal(EventStreamContentType, rec.Header().Get("Content-Type"))
+	ass.Equal("no-cache", rec.Header().Get("Cache-Control"))
+	ass.Equal("retry: 1000\n\n"+
+		"id: 1\nevent: greeting\ndata: hello\ndata: world\n\n"+
+		"id: 2\nretry: 2000\ndata: {\"count\":2}\n\n"+
+		"data: raw\n\n", rec.Body.String())
+	ass.True(rec.Flushed)
+}
+
+func TestStreamWritesEventSeq(t *testing.T) {
+	var events iter.Seq[Event] = slices.Values([]Event{{ID: "a\nb", Data: "x"}})
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response { return OK(events) })
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	assert.Equal(t, "id: ab\ndata: x\n\n", rec.Body.String())
+}
+
+func TestStreamSplitsDataOnEveryLineBreak(t *testing.T) {
+	ass := assert.New(t)
+	var events iter.Seq[Event] = slices.Values([]Event{
+		{Data: "x\revent: admin\nid: 0"},
+		{Data: "a\r\nb\r\rc"},
+	})
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response { return OK(events) })
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	// A lone CR must not let data inject other fields
+	ass.Equal("data: x\ndata: event: admin\ndata: id: 0\n\n"+
+		"data: a\ndata: b\ndata: \ndata: c\n\n", rec.Body.String())
+	ass.NotContains(rec.Body.String(), "\r")
+}
+
+func TestStreamResumesAfterLastEventID(t *testing.T) {
+	history := []Event{{ID: "1", Data: "a"}, {ID: "2", Data: "b"}, {ID: "3", Data: "c"}}
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		i := slices.IndexFunc(history, func(e Event) bool { return e.ID == LastEventID(r) })
+		return OK(iter.Seq[Event](slices.Values(history[i+1:])))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, "id: 3\ndata: c\n\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events?lastEventId=1", nil))
+	assert.Equal(t, "id: 2\ndata: b\n\nid: 3\ndata: c\n\n", rec.Body.String())
+}
+
+func TestStreamSendsHeartbeats(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 70*time.Millisecond)
+	defer cancel()
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response {
+		return OK(&EventStream{Events: make(chan Event), Heartbeat: 20 * time.Millisecond})
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+
+	assert.GreaterOrEqual(t, strings.Count(rec.Body.String(), ": heartbeat\n\n"), 2)
+}
+
+func TestStreamStopsEventsWhenClientIsGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	var events iter.Seq[Event] = func(yield func(Event) bool) {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			if i == 1 {
+				cancel()
+			}
+			if !yield(Event{Data: "tick"}) {
+				return
+			}
+		}
+	}
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response { return OK(events) })
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx))
+	}()
+
+	for _, ch := range []chan struct{}{done, stopped} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			require.Fail(t, "event stream did not stop")
+		}
+	}
+}
+
+func TestStreamAbortsOnEventEncodingError(t *testing.T) {
+	ch := make(chan Event, 1)
+	ch <- Event{Data: math.NaN()}
+	close(ch)
+
+	handler := Stream(func(w http.ResponseWriter, r *http.Request) *Response { return OK(ch) })
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	})
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultTimeoutHeader is the conventional header a client can use to shorten its deadline.
+const DefaultTimeoutHeader = "X-Request-Timeout"
+
+// TimeoutConfig configures TimeoutMiddleware.
+type TimeoutConfig struct {
+	// Timeout is the deadline of the route (required)
+	Timeout time.Duration
+	// Header is read for a client provided timeout, e.g. DefaultTimeoutHeader or "grpc-timeout".
+	// Empty means that clients cannot set the timeout
+	Header string
+	// MinTimeout and MaxTimeout bound the client provided timeout (default MaxTimeout: Timeout)
+	MinTimeout time.Duration
+	MaxTimeout time.Duration
+	// StatusCode is either http.StatusServiceUnavailable (default) or http.StatusGatewayTimeout
+	StatusCode int
+}
+
+// TimeoutMiddleware returns an HTTP middleware running the next handler with a deadline.
+//
+// Behavior:
+//   - Sets a context deadline of Timeout, or of the value of the Header (clamped between
+//     MinTimeout and MaxTimeout). The value uses the gRPC format: an integer followed by a unit
+//     among H, M, S, m (milliseconds), u (microseconds) and n (nanoseconds), e.g. "250m".
+//     A bare integer is read as seconds.
+//   - When the deadline is reached before the handler wrote anything, writes a 503
+//     (ServiceUnavailable) or 504 (GatewayTimeout) JSON error.
+//   - Once the deadline is reached, writes of the handler fail with http.ErrHandlerTimeout,
+//     so they never race with the timeout response.
+//   - Panics of the handler are propagated so that RecoveryMiddleware can catch them.
+//     ⚠️ The handler runs in its own goroutine and must honor r.Context() to stop working.
+//
+// ⚠️ A zero or negative Timeout, or a StatusCode other than 503 and 504, panics when the middleware
+// is built, it is a programming error.
+//
+// Example usage:
+//
+//	http.Handle("/api", TimeoutMiddleware(TimeoutConfig{Timeout: 5 * time.Second})(JSON(myHandler)))
+func TimeoutMiddleware(cfg TimeoutConfig) func(http.Handler) http.Handler {
+	if cfg.Timeout <= 0 {
+		panic("http: TimeoutConfig.Timeout must be positive")
+	}
+	switch cfg.StatusCode {
+	case 0, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		panic("http: TimeoutConfig.StatusCode must be 503 or 504, got " + strconv.Itoa(cfg.StatusCode))
+	}
+	maxTimeout := cfg.MaxTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = cfg.Timeout
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := cfg.Timeout
+			if cfg.Header != "" {
+				if d, ok := ParseTimeout(r.Header.Get(cfg.Header)); ok {
+					timeout = min(max(d, cfg.MinTimeout), maxTimeout)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, header: make(http.Header)}
+			done := make(chan struct{})
+			panicChan := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- p
+						return
+					}
+					close(done)
+				}()
+				next.ServeHTTP(tw, r)
+			}()
+
+			select {
+			case p := <-panicChan:
+				panic(p)
+
+			case <-done:
+				tw.finish()
+
+			case <-ctx.Done():
+				tw.timeout(cfg.StatusCode, errors.Is(ctx.Err(), context.DeadlineExceeded))
+			}
+		})
+	}
+}
+
+// grpcTimeoutUnits maps the units of the gRPC timeout format to durations.
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// ParseTimeout parses a timeout in the gRPC format ("100m", "5S"...) or a bare number of seconds.
+func ParseTimeout(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	unit, ok := grpcTimeoutUnits[v[len(v)-1]]
+	digits := v[:len(v)-1]
+	if !ok {
+		unit, digits = time.Second, v
+	}
+
+	// The gRPC format allows at most 8 digits
+	if len(digits) > 8 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// timeoutWriter guards the ResponseWriter shared by the handler goroutine and the timeout.
+// The handler gets its own header map, copied on the first write.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+// Flush forwards to the underlying writer when it supports streaming.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	copyHeader(tw.w.Header(), tw.header)
+	tw.wroteHeader = true
+	tw.w.WriteHeader(code)
+}
+
+// finish copies the headers of a handler that returned without writing anything.
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.wroteHeader {
+		copyHeader(tw.w.Header(), tw.header)
+	}
+}
+
+// timeout blocks any further write and answers with an error if nothing was sent yet.
+// A request canceled by the client gets no response.
+func (tw *timeoutWriter) timeout(statusCode int, deadlineExceeded bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	if tw.wroteHeader || !deadlineExceeded {
+		return
+	}
+
+	if statusCode == http.StatusGatewayTimeout {
+		respondWithJSON(tw.w, GatewayTimeout("request timed out"))
+		return
+	}
+	respondWithJSON(tw.w, ServiceUnavailable("request timed out"))
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+}
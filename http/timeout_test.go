@@ -0,0 +1,144 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeout(t *testing.T) {
+	ass := assert.New(t)
+
+	d, ok := ParseTimeout("250m")
+	ass.True(ok)
+	ass.Equal(250*time.Millisecond, d)
+
+	d, ok = ParseTimeout("2S")
+	ass.True(ok)
+	ass.Equal(2*time.Second, d)
+
+	d, ok = ParseTimeout("3")
+	ass.True(ok)
+	ass.Equal(3*time.Second, d)
+
+	_, ok = ParseTimeout("1.5s")
+	ass.False(ok)
+	_, ok = ParseTimeout("123456789m")
+	ass.False(ok)
+	_, ok = ParseTimeout("")
+	ass.False(ok)
+}
+
+func TestTimeoutWritesErrorWhenNothingWasWritten(t *testing.T) {
+	ass := assert.New(t)
+
+	lateWrite := make(chan error, 1)
+	handler := TimeoutMiddleware(TimeoutConfig{Timeout: 10 * time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write([]byte("too late"))
+		lateWrite <- err
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusServiceUnavailable, rec.Code)
+
+	var body map[string]string
+	ass.NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	ass.Equal("service unavailable", body["message"])
+	ass.Equal("request timed out", body["details"])
+
+	ass.ErrorIs(<-lateWrite, http.ErrHandlerTimeout)
+	ass.NotContains(rec.Body.String(), "too late")
+}
+
+func TestTimeoutGatewayTimeout(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := TimeoutMiddleware(TimeoutConfig{
+		Timeout:    time.Minute,
+		Header:     DefaultTimeoutHeader,
+		StatusCode: http.StatusGatewayTimeout,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	// The client asks for a shorter deadline
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DefaultTimeoutHeader, "10m")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusGatewayTimeout, rec.Code)
+}
+
+func TestTimeoutHeaderIsBounded(t *testing.T) {
+	ass := assert.New(t)
+
+	var deadline time.Duration
+	handler := TimeoutMiddleware(TimeoutConfig{
+		Timeout:    time.Second,
+		Header:     "grpc-timeout",
+		MinTimeout: 100 * time.Millisecond,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, _ := r.Context().Deadline()
+		deadline = time.Until(d)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("grpc-timeout", "1H")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	ass.LessOrEqual(deadline, time.Second)
+	ass.Greater(deadline, 500*time.Millisecond)
+
+	req.Header.Set("grpc-timeout", "1n")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	ass.LessOrEqual(deadline, 100*time.Millisecond)
+	ass.Greater(deadline, 50*time.Millisecond)
+}
+
+func TestTimeoutKeepsHandlerResponse(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := TimeoutMiddleware(TimeoutConfig{Timeout: time.Second})(JSON(func(w http.ResponseWriter, r *http.Request) *Response {
+		return Created("ok").AddHeader("X-Test", "123")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	ass.Equal(http.StatusCreated, rec.Code)
+	ass.Equal("123", rec.Header().Get("X-Test"))
+	ass.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+}
+
+func TestTimeoutPropagatesPanic(t *testing.T) {
+	ass := assert.New(t)
+
+	handler := TimeoutMiddleware(TimeoutConfig{Timeout: time.Second})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	ass.PanicsWithValue("boom", func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestTimeoutRejectsInvalidConfig(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Panics(func() { TimeoutMiddleware(TimeoutConfig{}) })
+	ass.Panics(func() { TimeoutMiddleware(TimeoutConfig{Timeout: -time.Second}) })
+	ass.Panics(func() {
+		TimeoutMiddleware(TimeoutConfig{Timeout: time.Second, StatusCode: http.StatusInternalServerError})
+	})
+	ass.NotPanics(func() { TimeoutMiddleware(TimeoutConfig{Timeout: time.Second, StatusCode: http.StatusGatewayTimeout}) })
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+)
+
+// Sentinel errors returned (usually wrapped) by handlers, mapped to their status by ErrorRegistry.
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrUnprocessable   = errors.New("unprocessable entity")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUnavailable     = errors.New("service unavailable")
+)
+
+// TypedConfig configures TypedWith.
+type TypedConfig struct {
+	// Bind configures the decoding of the request
+	Bind BindConfig
+	// StatusCode of successful responses (default: 200, or 204 when Res is struct{})
+	StatusCode int
+	// Errors maps the returned errors to responses (default: DefaultErrorRegistry)
+	Errors *ErrorRegistry
+}
+
+// Typed adapts a typed function to an http.HandlerFunc, see TypedWith.
+//
+// Example usage:
+//
+//	type GetUser struct {
+//		ID string `path:"id" validate:"required"`
+//	}
+//
+//	mux.HandleFunc("GET /users/{id}", Typed(func(ctx context.Context, req GetUser) (User, error) {
+//		user, ok := users[req.ID]
+//		if !ok {
+//			return User{}, fmt.Errorf("user %s: %w", req.ID, ErrNotFound)
+//		}
+//		return user, nil
+//	}))
+func Typed[Req, Res any](fn func(ctx context.Context, req Req) (Res, error)) http.HandlerFunc {
+	return TypedWith(nil, TypedConfig{}, fn)
+}
+
+// TypedWith adapts a typed function to an http.HandlerFunc.
+//
+// Behavior:
+//   - Decodes and validates the request into Req with BindWith (Req must be a struct,
+//     use struct{} when there is no input). Binding failures answer 400, 413, 415 or 422.
+//     ⚠️ A Req that is not a struct, or has invalid validate tags, panics when TypedWith is called.
+//   - Calls fn with the request context.
+//   - Encodes Res as JSON with cfg.StatusCode.
+//   - Maps the returned error to a response with cfg.Errors, e.g. a wrapped ErrNotFound
+//     answers 404 and an unknown error 500 without exposing it (see ErrorRegistry).
+//     The error is logged when logger is not nil, at ERROR level for server errors.
+//
+// Example usage:
+//
+//	mux.HandleFunc("POST /users", TypedWith(logger, TypedConfig{StatusCode: http.StatusCreated}, createUser))
+func TypedWith[Req, Res any](logger *slog.Logger, cfg TypedConfig, fn func(ctx context.Context, req Req) (Res, error)) http.HandlerFunc {
+	reqType := reflect.TypeFor[Req]()
+	if reqType.Kind() != reflect.Struct {
+		panic("http: Typed expects a struct request type, got " + reqType.String())
+	}
+	mustCheckValidation(reqType)
+
+	statusCode := cfg.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+		if reflect.TypeFor[Res]() == reflect.TypeFor[struct{}]() {
+			statusCode = http.StatusNoContent
+		}
+	}
+
+	registry := cfg.Errors
+	if registry == nil {
+		registry = DefaultErrorRegistry
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, resp := BindWith[Req](r, cfg.Bind)
+		if resp != nil {
+			respond(w, r, resp)
+			return
+		}
+
+		res, err := fn(r.Context(), req)
+		if err != nil {
+			resp := registry.Response(err)
+			logMappedError(logger, r, err, resp)
+			respond(w, r, resp)
+			return
+		}
+
+		respond(w, r, &Response{Payload: res, StatusCode: statusCode})
+	}
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mama165/sdk-go/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+type typedGetUser struct {
+	ID string `path:"id" validate:"required"`
+}
+
+type typedUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func serveTyped(h http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/{id}", h)
+	mux.HandleFunc("/users", h)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func getUser(ctx context.Context, req typedGetUser) (typedUser, error) {
+	switch req.ID {
+	case "1":
+		return typedUser{ID: "1", Name: "Bob"}, nil
+	case "forbidden":
+		return typedUser{}, ErrForbidden
+	case "teapot":
+		return typedUser{}, &Response{Payload: map[string]string{"message": "teapot"}, StatusCode: http.StatusTeapot}
+	case "boom":
+		return typedUser{}, errors.New("database password leaked in error")
+	default:
+		return typedUser{}, fmt.Errorf("user %s: %w", req.ID, ErrNotFound)
+	}
+}
+
+func TestTypedEncodesResult(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := serveTyped(Typed(getUser), http.MethodGet, "/users/1", "")
+
+	ass.Equal(http.StatusOK, rec.Code)
+	ass.JSONEq(`{"id":"1","name":"Bob"}`, rec.Body.String())
+}
+
+func TestTypedMapsErrors(t *testing.T) {
+	ass := assert.New(t)
+
+	rec := serveTyped(Typed(getUser), http.MethodGet, "/users/42", "")
+	ass.Equal(http.StatusNotFound, rec.Code)
+	ass.JSONEq(`{"code":"not_found","message":"not found","details":"not found"}`, rec.Body.String())
+
+	rec = serveTyped(Typed(getUser), http.MethodGet, "/users/forbidden", "")
+	ass.Equal(http.StatusForbidden, rec.Code)
+
+	rec = serveTyped(Typed(getUser), http.MethodGet, "/users/teapot", "")
+	ass.Equal(http.StatusTeapot, rec.Code)
+}
+
+func TestTypedHidesAndLogsUnexpectedErrors(t *testing.T) {
+	ass := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := logs.GetLoggerFromBufferWithLogger(&buf, slog.LevelInfo)
+
+	rec := serveTyped(TypedWith(logger, TypedConfig{}, getUser), http.MethodGet, "/users/boom", "")
+
+	ass.Equal(http.StatusInternalServerError, rec.Code)
+	ass.NotContains(rec.Body.String(), "password")
+	ass.Contains(buf.String(), "database password leaked in error")
+}
+
+func TestTypedValidatesRequest(t *testing.T) {
+	ass := assert.New(t)
+
+	type createUser struct {
+		Name string `json:"name" validate:"required"`
+	}
+	create := func(ctx context.Context, req createUser) (typedUser, error) {
+		return typedUser{ID: "2", Name: req.Name}, nil
+	}
+	h := TypedWith(nil, TypedConfig{StatusCode: http.StatusCreated}, create)
+
+	rec := serveTyped(h, http.MethodPost, "/users", `{"name":""}`)
+	ass.Equal(http.StatusUnprocessableEntity, rec.Code)
+	ass.Contains(rec.Body.String(), `"field":"name"`)
+
+	rec = serveTyped(h, http.MethodPost, "/users", `{"name":"Alice"}`)
+	ass.Equal(http.StatusCreated, rec.Code)
+	ass.JSONEq(`{"id":"2","name":"Alice"}`, rec.Body.String())
+}
+
+func TestTypedEmptyResultIsNoContent(t *testing.T) {
+	deleteUser := func(ctx context.Context, req typedGetUser) (struct{}, error) {
+		return struct{}{}, nil
+	}
+
+	rec := serveTyped(Typed(deleteUser), http.MethodDelete, "/users/1", "")
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestTypedRejectsInvalidRequestTypesAtRegistration(t *testing.T) {
+	ass := assert.New(t)
+
+	ass.Panics(func() {
+		Typed(func(ctx context.Context, id string) (string, error) { return id, nil })
+	})
+
+	type badTag struct {
+		Name string `json:"name" validate:"shiny"`
+	}
+	ass.Panics(func() {
+		Typed(func(ctx context.Context, req badTag) (string, error) { return req.Name, nil })
+	})
+}
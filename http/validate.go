@@ -0,0 +1,372 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError describes an invalid field of a request.
+type FieldError struct {
+	// Field is the path of the field as seen by the client, e.g. "items[0].name"
+	Field string `json:"field"`
+	// Rule is the failed rule, e.g. "required", "min" or "type"
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// FieldErrors lists every invalid field of a request.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// fieldErrorsResponse lists the field errors in the usual {"message", "details"} payload.
+func fieldErrorsResponse(statusCode int, errs FieldErrors) *Response {
+	details := "1 invalid field"
+	if len(errs) > 1 {
+		details = strconv.Itoa(len(errs)) + " invalid fields"
+	}
+	return &Response{
+		Payload: map[string]any{
+			"code":    CodeValidationFailed,
+			"message": strings.ToLower(http.StatusText(statusCode)),
+			"details": details,
+			"errors":  errs,
+		},
+		StatusCode: statusCode,
+	}
+}
+
+// Validate checks the `validate` struct tags of v, a struct or a pointer to a struct,
+// and returns FieldErrors listing every invalid field, or nil.
+//
+// Rules, separated by commas:
+//   - required: the field is not the zero value (non-nil pointer, non-empty string or slice).
+//   - omitempty: the other rules are skipped when the field is the zero value.
+//   - min=N, max=N, len=N: length of strings (in characters), slices and maps, or value of numbers.
+//   - email: a bare email address, e.g. "bob@example.com".
+//   - url: an absolute URL with a scheme and a host.
+//   - oneof=a b c: one of the space separated values.
+//
+// Nil pointers skip every rule but required. Nested structs, and slices of structs, are validated too.
+// Fields are named after their json tag (or form, query, path tag).
+// ⚠️ An unknown rule, or a rule that does not apply to the field type, panics the first time
+// the struct type is validated (when the route is registered for Typed), it is a programming error.
+//
+// Example usage:
+//
+//	type Signup struct {
+//		Email string `json:"email" validate:"required,email"`
+//		Age   int    `json:"age" validate:"omitempty,min=18"`
+//	}
+//	if err := Validate(signup); err != nil { ... }
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs FieldErrors
+	validateStruct(rv, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validatedField is a struct field with its parsed rules.
+type validatedField struct {
+	index    int
+	name     string
+	embedded bool
+	rules    []validationRule
+}
+
+type validationRule struct {
+	name  string
+	param string
+}
+
+var validatedFieldsCache sync.Map // reflect.Type → []validatedField
+
+func validatedFields(t reflect.Type) []validatedField {
+	if cached, ok := validatedFieldsCache.Load(t); ok {
+		return cached.([]validatedField)
+	}
+
+	var fields []validatedField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		field := validatedField{index: i, name: fieldName(f), embedded: f.Anonymous && fieldTagName(f) == ""}
+		if tag != "" {
+			for _, part := range strings.Split(tag, ",") {
+				name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+				rule := validationRule{name: name, param: param}
+				if err := rule.check(f.Type); err != nil {
+					panic(fmt.Sprintf("http: %s on %s.%s", err, t.Name(), f.Name))
+				}
+				field.rules = append(field.rules, rule)
+			}
+		}
+		fields = append(fields, field)
+	}
+
+	validatedFieldsCache.Store(t, fields)
+
+	// Nested structs are checked at once, not when a request first fills them
+	for _, field := range fields {
+		if nested := nestedStruct(t.Field(field.index).Type); nested != nil {
+			validatedFields(nested)
+		}
+	}
+	return fields
+}
+
+// check reports an unknown rule, or a rule that cannot apply to a field of type t.
+func (rule validationRule) check(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch rule.name {
+	case "required", "omitempty":
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(rule.param, 64); err != nil {
+			return fmt.Errorf("invalid %s parameter %q", rule.name, rule.param)
+		}
+		switch t.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("%s does not apply to %s", rule.name, t.Kind())
+		}
+	case "email", "url":
+		if t.Kind() != reflect.String {
+			return fmt.Errorf("%s does not apply to %s", rule.name, t.Kind())
+		}
+	case "oneof":
+		if len(strings.Fields(rule.param)) == 0 {
+			return errors.New("oneof needs at least one value")
+		}
+	default:
+		return fmt.Errorf("unknown validation rule %q", rule.name)
+	}
+	return nil
+}
+
+// nestedStruct returns the struct type validated within a field of type t, or nil.
+func nestedStruct(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
+// mustCheckValidation parses the validate tags of t, and of the structs it contains,
+// so that an invalid tag panics when a route is registered rather than on its first request.
+func mustCheckValidation(t reflect.Type) {
+	if nested := nestedStruct(t); nested != nil {
+		validatedFields(nested)
+	}
+}
+
+// fieldName is the name of the field as seen by the client.
+func fieldName(f reflect.StructField) string {
+	if name := fieldTagName(f); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+func fieldTagName(f reflect.StructField) string {
+	for _, key := range []string{"json", "form", "query", "path"} {
+		name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *FieldErrors) {
+	for _, field := range validatedFields(v.Type()) {
+		fv := v.Field(field.index)
+		path := prefix + field.name
+		if field.embedded {
+			// Embedded fields are flattened, as in JSON
+			path = strings.TrimSuffix(prefix, ".")
+		}
+
+		if applyRules(fv, path, field.rules, errs) {
+			validateNested(fv, path, errs)
+		}
+	}
+}
+
+// validateNested descends into structs and slices of structs.
+func validateNested(v reflect.Value, path string, errs *FieldErrors) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeFor[time.Time]() {
+			return
+		}
+		prefix := path + "."
+		if path == "" {
+			prefix = ""
+		}
+		validateStruct(v, prefix, errs)
+
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			validateNested(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	}
+}
+
+// applyRules validates a field, it returns false when the field is invalid or nil.
+func applyRules(v reflect.Value, path string, rules []validationRule, errs *FieldErrors) bool {
+	addError := func(rule, message string) bool {
+		*errs = append(*errs, FieldError{Field: path, Rule: rule, Message: message})
+		return false
+	}
+
+	required := slices.ContainsFunc(rules, func(r validationRule) bool { return r.name == "required" })
+	omitempty := slices.ContainsFunc(rules, func(r validationRule) bool { return r.name == "omitempty" })
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if required {
+				return addError("required", "is required")
+			}
+			return false
+		}
+		v = v.Elem()
+	} else if v.IsZero() {
+		if required {
+			return addError("required", "is required")
+		}
+		if omitempty {
+			return false
+		}
+	}
+
+	for _, rule := range rules {
+		switch rule.name {
+		case "min", "max", "len":
+			if msg, ok := checkSize(v, rule); !ok {
+				return addError(rule.name, msg)
+			}
+
+		case "email":
+			addr, err := mail.ParseAddress(v.String())
+			if err != nil || addr.Address != v.String() {
+				return addError("email", "must be a valid email address")
+			}
+
+		case "url":
+			u, err := url.Parse(v.String())
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return addError("url", "must be an absolute URL")
+			}
+
+		case "oneof":
+			options := strings.Fields(rule.param)
+			if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+				return addError("oneof", "must be one of: "+strings.Join(options, ", "))
+			}
+		}
+	}
+	return true
+}
+
+// checkSize evaluates min, max and len against the length or the value of v.
+func checkSize(v reflect.Value, rule validationRule) (string, bool) {
+	limit, err := strconv.ParseFloat(rule.param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("http: invalid %s parameter %q", rule.name, rule.param))
+	}
+
+	var actual float64
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		actual = float64(utf8.RuneCountInString(v.String()))
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		actual = float64(v.Len())
+		unit = " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = v.Float()
+	default:
+		panic(fmt.Sprintf("http: %s does not apply to %s", rule.name, v.Kind()))
+	}
+
+	switch rule.name {
+	case "min":
+		if actual < limit {
+			if unit == "" {
+				return "must be at least " + rule.param, false
+			}
+			return "must have at least " + rule.param + unit, false
+		}
+	case "max":
+		if actual > limit {
+			if unit == "" {
+				return "must be at most " + rule.param, false
+			}
+			return "must have at most " + rule.param + unit, false
+		}
+	case "len":
+		if actual != limit {
+			if unit == "" {
+				return "must be " + rule.param, false
+			}
+			return "must have exactly " + rule.param + unit, false
+		}
+	}
+	return "", true
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the Prometheus text exposition format version 0.0.4.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler exposes the registry in the Prometheus text format.
+//
+// Example usage:
+//
+//	reg := metrics.NewRegistry()
+//	http.Handle("GET /metrics", reg.Handler())
+func (reg *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = reg.Write(w)
+	})
+}
+
+// Write renders every metric, sorted by name and label values.
+func (reg *Registry) Write(w io.Writer) error {
+	reg.mu.Lock()
+	families := make([]*family, 0, len(reg.families))
+	for _, f := range reg.families {
+		families = append(families, f)
+	}
+	reg.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) write(w *bufio.Writer) {
+	f.mu.RLock()
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	f.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+
+	w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + string(f.typ) + "\n")
+
+	for _, s := range all {
+		if f.typ != typeHistogram {
+			w.WriteString(f.name + formatLabels(f.labels, s.labelValues, "", "") + " " + formatFloat(s.value.load()) + "\n")
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i].Load()
+			w.WriteString(f.name + "_bucket" + formatLabels(f.labels, s.labelValues, "le", formatFloat(upper)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		cumulative += s.counts[len(f.buckets)].Load()
+		w.WriteString(f.name + "_bucket" + formatLabels(f.labels, s.labelValues, "le", "+Inf") + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		w.WriteString(f.name + "_sum" + formatLabels(f.labels, s.labelValues, "", "") + " " + formatFloat(s.value.load()) + "\n")
+		w.WriteString(f.name + "_count" + formatLabels(f.labels, s.labelValues, "", "") + " " + strconv.FormatUint(cumulative, 10) + "\n")
+	}
+}
+
+// formatLabels renders {name="value",...}, with an optional extra label (the histogram "le").
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="` + escapeLabelValue(values[i]) + `"`)
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(extraName + `="` + extraValue + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
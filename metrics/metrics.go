@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// SizeBuckets are size buckets in bytes, from 100 B to 10 MB.
+var SizeBuckets = []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// Registry holds metric families and renders them in the Prometheus text format.
+// It has no dependency on the Prometheus client library.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family is a metric name with its labelled series.
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+// series is a single labelled time series.
+type series struct {
+	labelValues []string
+	value       atomicFloat     // counter and gauge value, histogram sum
+	counts      []atomic.Uint64 // histogram buckets (non cumulative), the last one is +Inf
+}
+
+// register returns the family called name, creating it if needed.
+// Registering the same name with another type or other labels panics.
+func (reg *Registry) register(name, help string, typ metricType, buckets []float64, labels []string) *family {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if f, ok := reg.families[name]; ok {
+		if f.typ != typ || !slices.Equal(f.labels, labels) || !slices.Equal(f.buckets, buckets) {
+			panic(fmt.Sprintf("metrics: %q already registered with another definition", name))
+		}
+		return f
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	reg.families[name] = f
+	return f
+}
+
+// with returns the series of the label values, creating it if needed.
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %q expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	s = &series{labelValues: slices.Clone(values)}
+	if f.typ == typeHistogram {
+		s.counts = make([]atomic.Uint64, len(f.buckets)+1)
+	}
+	f.series[key] = s
+	return s
+}
+
+// CounterVec is a counter family partitioned by labels.
+type CounterVec struct{ f *family }
+
+// Counter only goes up.
+type Counter struct{ s *series }
+
+// NewCounterVec registers a counter, e.g. NewCounterVec("jobs_total", "Processed jobs.", "queue").
+func (reg *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: reg.register(name, help, typeCounter, nil, labels)}
+}
+
+// With returns the counter of the label values, given in the registration order.
+func (v *CounterVec) With(values ...string) *Counter {
+	return &Counter{s: v.f.with(values)}
+}
+
+// Inc adds 1.
+func (c *Counter) Inc() {
+	c.s.value.add(1)
+}
+
+// Add adds a non negative value.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.s.value.add(delta)
+}
+
+// GaugeVec is a gauge family partitioned by labels.
+type GaugeVec struct{ f *family }
+
+// Gauge goes up and down.
+type Gauge struct{ s *series }
+
+// NewGaugeVec registers a gauge.
+func (reg *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: reg.register(name, help, typeGauge, nil, labels)}
+}
+
+// With returns the gauge of the label values, given in the registration order.
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return &Gauge{s: v.f.with(values)}
+}
+
+// Set replaces the value.
+func (g *Gauge) Set(value float64) {
+	g.s.value.set(value)
+}
+
+// Add adds delta, which can be negative.
+func (g *Gauge) Add(delta float64) {
+	g.s.value.add(delta)
+}
+
+// Inc adds 1.
+func (g *Gauge) Inc() {
+	g.s.value.add(1)
+}
+
+// Dec subtracts 1.
+func (g *Gauge) Dec() {
+	g.s.value.add(-1)
+}
+
+// HistogramVec is a histogram family partitioned by labels.
+type HistogramVec struct{ f *family }
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram with sorted upper bounds (DefaultBuckets if nil).
+func (reg *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %q buckets must be sorted", name))
+	}
+	return &HistogramVec{f: reg.register(name, help, typeHistogram, buckets, labels)}
+}
+
+// With returns the histogram of the label values, given in the registration order.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return &Histogram{s: v.f.with(values), buckets: v.f.buckets}
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.buckets, value)
+	h.s.counts[i].Add(1)
+	h.s.value.add(value)
+}
+
+// atomicFloat is a float64 updated with compare-and-swap.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) set(value float64) {
+	f.bits.Store(math.Float64bits(value))
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextFormat(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := NewRegistry()
+	jobs := reg.NewCounterVec("jobs_total", "Processed jobs.", "queue")
+	jobs.With("emails").Inc()
+	jobs.With("emails").Add(2)
+	jobs.With(`we"ird`).Inc()
+
+	workers := reg.NewGaugeVec("workers", "Busy\nworkers.")
+	workers.With().Set(3)
+	workers.With().Dec()
+
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	latency.With("read").Observe(0.05)
+	latency.With("read").Observe(0.1)
+	latency.With("read").Observe(5)
+
+	var buf bytes.Buffer
+	ass.NoError(reg.Write(&buf))
+
+	ass.Equal(`# HELP jobs_total Processed jobs.
+# TYPE jobs_total counter
+jobs_total{queue="emails"} 3
+jobs_total{queue="we\"ird"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="read",le="0.1"} 2
+latency_seconds_bucket{op="read",le="1"} 2
+latency_seconds_bucket{op="read",le="+Inf"} 3
+latency_seconds_sum{op="read"} 5.15
+latency_seconds_count{op="read"} 3
+# HELP workers Busy\nworkers.
+# TYPE workers gauge
+workers 2
+`, buf.String())
+}
+
+func TestRegisterConflictPanics(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := NewRegistry()
+	reg.NewCounterVec("requests_total", "Requests.", "method")
+
+	ass.NotPanics(func() { reg.NewCounterVec("requests_total", "Requests.", "method") })
+	ass.Panics(func() { reg.NewGaugeVec("requests_total", "Requests.", "method") })
+	ass.Panics(func() { reg.NewCounterVec("requests_total", "Requests.", "route") })
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := NewRegistry()
+	vec := reg.NewCounterVec("requests_total", "Requests.", "method", "route")
+	ass.Panics(func() { vec.With("GET") })
+}
+
+func TestHandler(t *testing.T) {
+	ass := assert.New(t)
+
+	reg := NewRegistry()
+	reg.NewCounterVec("up", "Up.").With().Inc()
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	ass.Equal(ContentType, rec.Header().Get("Content-Type"))
+	ass.Contains(rec.Body.String(), "up 1\n")
+}